@@ -0,0 +1,386 @@
+// Command swcg answers questions about the card database.
+//
+//	swcg cards -faction Jedi -type Unit -cost 2-4 -trait ForceUser -columns Name,Cost,Force -sort -Cost,Number
+//	swcg cards -q 'faction:Jedi type:Unit cost<=3 -keyword:Elite sort:cost,-force'
+//	swcg cards -group Sets -agg count,sum:Force
+//	swcg cards -pivot Faction,Type,Cost,avg
+//	swcg sets
+//	swcg traits -sort -Cards
+//	swcg keywords
+//	swcg synergies -top 10
+//	swcg synergies -sets -format dot > sets.dot
+//	swcg search shield '"edge stack"'
+//
+// Every subcommand prints a table whose rows can be sorted with -sort, a
+// comma separated list of columns, descending when prefixed with '-'.
+package main
+
+import "flag"
+import "fmt"
+import "os"
+import "sort"
+import "strconv"
+import "strings"
+
+import "github.com/sthilaid/swcg"
+
+var commands = []struct {
+	name  string
+	usage string
+	run   func(cache *swcg.DataCache, args []string) error
+}{
+	{"cards",     "list the cards matching the filters",     cardsCommand},
+	{"sets",      "list the objective sets",                 setsCommand},
+	{"traits",    "count the cards having each trait",       traitsCommand},
+	{"keywords",  "count the cards having each keyword",     keywordsCommand},
+	{"synergies", "rank or export the card synergy graph",   synergiesCommand},
+	{"search",    "full-text search of the card texts",      searchCommand},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: swcg command [flags]\n\ncommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.usage)
+	}
+	fmt.Fprintln(os.Stderr, "\nrun swcg command -h for the flags of a command")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			_, cache := swcg.AnalyzeDB(swcg.CreateDB())
+			if err := c.run(cache, os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+			return
+		}
+	}
+	fmt.Fprintln(os.Stderr, "unknown command: "+os.Args[1])
+	usage()
+	os.Exit(2)
+}
+
+// Case insensitive lookup of an enum value by name.
+func lookup(names []string, name string, what string) (int, error) {
+	for i, n := range names {
+		if strings.EqualFold(n, name) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown %s %q, expected one of: %s", what, name, strings.Join(names, ", "))
+}
+
+// Sorts the table on the given columns, then prints it.
+func printSorted(data *swcg.DataCollection, sortKeys string) error {
+	if sortKeys != "" {
+		entries := make([]swcg.RowSortEntry, 0)
+		for _, key := range strings.Split(sortKeys, ",") {
+			key = strings.TrimSpace(key)
+			order := swcg.SortOrder_Ascending
+			if strings.HasPrefix(key, "-") {
+				key, order = key[1:], swcg.SortOrder_Descending
+			}
+			index := data.ColumnIndex(key)
+			if index < 0 {
+				return fmt.Errorf("unknown sort column %q", key)
+			}
+			entries = append(entries, swcg.CreateRowSortEntry(index, order))
+		}
+		data.Sort(entries)
+	}
+	fmt.Print(data.Print())
+	return nil
+}
+
+// Cards ----------------------------------------------------------------------
+
+// Parses "n", "min-max", "min-" or "-max".
+func parseRange(s string) (int, int, error) {
+	low, high, isRange := strings.Cut(s, "-")
+	if !isRange {
+		high = low
+	}
+	bounds := [2]int{0, 1 << 30}
+	for i, b := range []string{low, high} {
+		if b = strings.TrimSpace(b); b != "" {
+			n, err := strconv.Atoi(b)
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid range %q", s)
+			}
+			bounds[i] = n
+		}
+	}
+	return bounds[0], bounds[1], nil
+}
+
+func cardsCommand(cache *swcg.DataCache, args []string) error {
+	flags := flag.NewFlagSet("cards", flag.ExitOnError)
+	faction := flags.String("faction", "", "faction ("+strings.Join(swcg.FactionNames[:], ", ")+")")
+	cardType := flags.String("type", "", "card type ("+strings.Join(swcg.CardTypeNames[:], ", ")+")")
+	cost := flags.String("cost", "", "cost or cost range, e.g. 2 or 1-3")
+	trait := flags.String("trait", "", "trait the cards must have")
+	keyword := flags.String("keyword", "", "keyword the cards must have")
+	name := flags.String("name", "", "case insensitive substring of the card names")
+	columns := flags.String("columns", strings.Join(swcg.DefaultCardColumns, ","), "columns to print ("+strings.Join(swcg.CardColumnNames(), ", ")+")")
+	sortKeys := flags.String("sort", "", "columns to sort on, descending when prefixed with '-'")
+	query := flags.String("q", "", "card query, e.g. 'faction:Jedi cost<=3 -keyword:Elite sort:-force'")
+	group := flags.String("group", "", "columns to group the cards by, e.g. Faction,Type")
+	aggregates := flags.String("agg", "count", "aggregates of the groups, e.g. count,avg:Cost,max:Force")
+	pivot := flags.String("pivot", "", "pivot table row,column,value,function, e.g. Faction,Type,Cost,avg")
+	flags.Parse(args)
+
+	cardQuery, err := swcg.ParseCardQuery(*query)
+	if err != nil {
+		return err
+	}
+	predicates := []swcg.CardPredicate{cardQuery.Predicate}
+	if *faction != "" {
+		f, err := lookup(swcg.FactionNames[:], *faction, "faction")
+		if err != nil {
+			return err
+		}
+		predicates = append(predicates, func(c *swcg.Card) bool { return c.Faction == swcg.CardFaction(f) })
+	}
+	if *cardType != "" {
+		t, err := lookup(swcg.CardTypeNames[:], *cardType, "card type")
+		if err != nil {
+			return err
+		}
+		predicates = append(predicates, swcg.TypePredicate(swcg.CardType(t)))
+	}
+	if *cost != "" {
+		low, high, err := parseRange(*cost)
+		if err != nil {
+			return err
+		}
+		predicates = append(predicates, func(c *swcg.Card) bool { return c.Cost >= low && c.Cost <= high })
+	}
+	if *trait != "" {
+		t, err := lookup(swcg.TraitNames[:], *trait, "trait")
+		if err != nil {
+			return err
+		}
+		predicates = append(predicates, swcg.TraitPredicate(swcg.CardTraitType(t)))
+	}
+	if *keyword != "" {
+		k, err := lookup(swcg.KeywordNames[:], *keyword, "keyword")
+		if err != nil {
+			return err
+		}
+		predicates = append(predicates, swcg.KeywordPredicate(swcg.CardKeywordType(k)))
+	}
+	if *name != "" {
+		predicates = append(predicates, func(c *swcg.Card) bool {
+			return strings.Contains(strings.ToLower(c.Name), strings.ToLower(*name))
+		})
+	}
+
+	selected, err := swcg.CardColumnsByName(strings.Split(*columns, ",")...)
+	if err != nil {
+		return err
+	}
+	if *group != "" || *pivot != "" {
+		selected = swcg.CardColumns
+	}
+
+	cardQuery.Predicate = func(c *swcg.Card) bool {
+		for _, p := range predicates {
+			if !p(c) {
+				return false
+			}
+		}
+		return true
+	}
+	data, err := cardQuery.Run(cache, selected)
+	if err == nil && *pivot != "" {
+		data, err = pivotCards(data, *pivot)
+	} else if err == nil && *group != "" {
+		data, err = groupCards(data, *group, *aggregates)
+	}
+	if err != nil {
+		return err
+	}
+	return printSorted(data, *sortKeys)
+}
+
+// Groups the cards by the comma separated columns, exploding the list
+// columns (traits, keywords, sets) so that a card counts in each of its
+// groups.
+func groupCards(data *swcg.DataCollection, group string, aggregates string) (*swcg.DataCollection, error) {
+	columns := strings.Split(group, ",")
+	var err error
+	for _, col := range columns {
+		if c, ok := swcg.CardColumnByName(strings.TrimSpace(col)); ok && isListColumn(c) {
+			if data, err = data.Explode(c.Name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	aggregations := make([]swcg.Aggregation, 0)
+	for _, a := range strings.Split(aggregates, ",") {
+		aggregation, err := swcg.ParseAggregation(a)
+		if err != nil {
+			return nil, err
+		}
+		aggregations = append(aggregations, aggregation)
+	}
+	groups, err := data.GroupBy(columns...)
+	if err != nil {
+		return nil, err
+	}
+	return groups.Aggregate(aggregations...)
+}
+
+// Parses "rowColumn,columnColumn,valueColumn,function".
+func pivotCards(data *swcg.DataCollection, pivot string) (*swcg.DataCollection, error) {
+	fields := strings.Split(pivot, ",")
+	if len(fields) != 4 {
+		return nil, fmt.Errorf("invalid pivot %q, expected row,column,value,function e.g. Faction,Type,Cost,avg", pivot)
+	}
+	aggregation, err := swcg.ParseAggregation(fields[3] + ":" + fields[2])
+	if err != nil {
+		return nil, err
+	}
+	for _, col := range fields[:2] {
+		if c, ok := swcg.CardColumnByName(strings.TrimSpace(col)); ok && isListColumn(c) {
+			if data, err = data.Explode(c.Name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return data.Pivot(fields[0], fields[1], aggregation.Column, aggregation.Func)
+}
+
+func isListColumn(c swcg.CardColumn) bool {
+	return c.Name == "Traits" || c.Name == "Keywords" || c.Name == "Sets"
+}
+
+// Sets, Traits and Keywords --------------------------------------------------
+
+func setsCommand(cache *swcg.DataCache, args []string) error {
+	flags := flag.NewFlagSet("sets", flag.ExitOnError)
+	sortKeys := flags.String("sort", "", "columns to sort on, descending when prefixed with '-'")
+	flags.Parse(args)
+
+	ids := make([]int, 0, len(*cache.SetMap))
+	for id := range *cache.SetMap {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	data := swcg.CreateDataCollection("Set", "Objective", "Faction", "Resources", "Health", "Cards")
+	for _, id := range ids {
+		set := (*cache.SetMap)[id]
+		objective, faction, resources, health := "-", "-", 0, 0
+		if o := set[0]; o != nil {
+			objective, faction, resources, health = o.Name, swcg.FactionNames[o.Faction], o.Ressources, o.Health
+		}
+		names := make([]string, 0, 5)
+		for _, c := range set[1:] {
+			if c != nil {
+				names = append(names, c.Name)
+			}
+		}
+		data.AddRow(id, objective, faction, resources, health, strings.Join(names, ", "))
+	}
+	return printSorted(data, *sortKeys)
+}
+
+func traitsCommand(cache *swcg.DataCache, args []string) error {
+	flags := flag.NewFlagSet("traits", flag.ExitOnError)
+	sortKeys := flags.String("sort", "", "columns to sort on, descending when prefixed with '-'")
+	flags.Parse(args)
+
+	data := swcg.CreateDataCollection("Trait", "Cards", "Synergies")
+	for i, name := range swcg.TraitNames {
+		t := swcg.CardTraitType(i)
+		if cards, synergies := len((*cache.TraitMap)[t]), len((*cache.TraitSynergyMap)[t]); cards+synergies > 0 {
+			data.AddRow(name, cards, synergies)
+		}
+	}
+	return printSorted(data, *sortKeys)
+}
+
+func keywordsCommand(cache *swcg.DataCache, args []string) error {
+	flags := flag.NewFlagSet("keywords", flag.ExitOnError)
+	sortKeys := flags.String("sort", "", "columns to sort on, descending when prefixed with '-'")
+	flags.Parse(args)
+
+	data := swcg.CreateDataCollection("Keyword", "Cards")
+	for i, name := range swcg.KeywordNames {
+		if cards := len((*cache.KeywordMap)[swcg.CardKeywordType(i)]); cards > 0 {
+			data.AddRow(name, cards)
+		}
+	}
+	return printSorted(data, *sortKeys)
+}
+
+// Synergies ------------------------------------------------------------------
+
+func synergiesCommand(cache *swcg.DataCache, args []string) error {
+	flags := flag.NewFlagSet("synergies", flag.ExitOnError)
+	top := flags.Int("top", 0, "only list the n most enabling cards")
+	sets := flags.Bool("sets", false, "aggregate the synergies by objective set")
+	format := flags.String("format", "text", "output format (text, dot, gexf)")
+	sortKeys := flags.String("sort", "", "columns to sort on, descending when prefixed with '-'")
+	flags.Parse(args)
+
+	graph := swcg.BuildSynergyGraph(cache)
+	view := graph.ObjectiveSetView(cache)
+	switch {
+	case *format == "dot" && *sets:  return view.WriteDOT(os.Stdout)
+	case *format == "dot":           return graph.WriteDOT(os.Stdout, cache)
+	case *format == "gexf" && *sets: return view.WriteGEXF(os.Stdout)
+	case *format == "gexf":          return graph.WriteGEXF(os.Stdout, cache)
+	case *format != "text":          return fmt.Errorf("unknown format %q", *format)
+	}
+
+	if *sets {
+		data := swcg.CreateDataCollection("From", "To", "Effect", "Weight")
+		for _, e := range view.Edges {
+			effect := "positive"
+			if !e.Positive {
+				effect = "opponent"
+			}
+			data.AddRow(e.From, e.To, effect, e.Weight)
+		}
+		return printSorted(data, *sortKeys)
+	}
+	data := swcg.CreateDataCollection("Number", "Name", "Enables", "Hinders", "Enabled")
+	for _, d := range graph.MostEnabling(*top) {
+		data.AddRow(d.Card, (*cache.CardMap)[d.Card].Name, d.Enables, d.Hinders, d.Enabled)
+	}
+	return printSorted(data, *sortKeys)
+}
+
+// Search ---------------------------------------------------------------------
+
+func searchCommand(cache *swcg.DataCache, args []string) error {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	top := flags.Int("top", 0, "only list the n best results")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		return fmt.Errorf("usage: swcg search [flags] word|\"phrase\"...")
+	}
+
+	query := make([]string, 0, flags.NArg())
+	for _, arg := range flags.Args() {
+		if strings.Contains(arg, " ") && !strings.Contains(arg, `"`) {
+			arg = `"` + arg + `"`
+		}
+		query = append(query, arg)
+	}
+	data := swcg.CreateDataCollection("Number", "Name", "Score")
+	for i, r := range cache.TextIndex.Search(strings.Join(query, " ")) {
+		if *top > 0 && i >= *top {
+			break
+		}
+		data.AddRow(r.Card.Number, r.Card.Name, r.Score)
+	}
+	fmt.Print(data.Print())
+	return nil
+}
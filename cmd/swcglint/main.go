@@ -0,0 +1,84 @@
+// Command swcglint validates the card database and prints every finding.
+//
+// It exits with status 1 when at least one error level finding is reported.
+package main
+
+import "flag"
+import "fmt"
+import "os"
+import "strings"
+
+import "github.com/sthilaid/swcg"
+
+func main() {
+	minSeverity := flag.String("severity", "info", "minimum severity to report (info, warning, error)")
+	ruleNames := flag.String("rules", "", "comma separated list of rules to run (default: all)")
+	listRules := flag.Bool("list", false, "list the available rules and exit")
+	dbPath := flag.String("db", "", "JSON card DB to lint (default: the built-in CreateDB)")
+	flag.Parse()
+
+	rules := swcg.DefaultLintRules()
+	if *listRules {
+		for _, r := range rules {
+			fmt.Println(swcg.SeverityNames[r.Severity()]+"\t"+r.Name())
+		}
+		return
+	}
+
+	severity := swcg.Severity_MAX
+	for i, name := range swcg.SeverityNames {
+		if name == *minSeverity {
+			severity = swcg.LintSeverity(i)
+		}
+	}
+	if severity == swcg.Severity_MAX {
+		fmt.Fprintln(os.Stderr, "unknown severity: "+*minSeverity)
+		os.Exit(2)
+	}
+
+	if *ruleNames != "" {
+		selected := make(swcg.LintRuleList, 0)
+		for _, name := range strings.Split(*ruleNames, ",") {
+			found := false
+			for _, r := range rules {
+				if r.Name() == strings.TrimSpace(name) {
+					selected = append(selected, r)
+					found = true
+				}
+			}
+			if !found {
+				fmt.Fprintln(os.Stderr, "unknown rule: "+name)
+				os.Exit(2)
+			}
+		}
+		rules = selected
+	}
+
+	db := swcg.CreateDB()
+	if *dbPath != "" {
+		f, err := os.Open(*dbPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		db, err = swcg.LoadDB(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	findings := swcg.FilterFindings(swcg.LintWithRules(db, rules), severity)
+	errorCount := 0
+	for _, f := range findings {
+		fmt.Println(f.String())
+		if f.Severity == swcg.Severity_Error {
+			errorCount++
+		}
+	}
+	fmt.Printf("%d finding(s), %d error(s)\n", len(findings), errorCount)
+	if errorCount > 0 {
+		os.Exit(1)
+	}
+}
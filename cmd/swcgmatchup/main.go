@@ -0,0 +1,76 @@
+// Command swcgmatchup plays every pairing of the given decks against each
+// other and prints the win-rate matrix with its confidence intervals.
+//
+// Each argument is a deck definition: [name=][faction:]setId,setId,...
+//
+//	swcgmatchup -games 2000 -ai greedy Yoda=Jedi:1,1,2,2,3,3,4,5,6,18 Luke=Jedi:1,1,2,2,3,3,4,4,5,6
+package main
+
+import "flag"
+import "fmt"
+import "os"
+
+import "github.com/sthilaid/swcg"
+
+func main() {
+	games := flag.Int("games", 1000, "games played per pairing")
+	ai := flag.String("ai", "greedy", "AI playing the decks (greedy, random, mcts)")
+	iterations := flag.Int("mcts-iterations", 200, "MCTS playouts per decision")
+	seed := flag.Uint64("seed", 1, "random seed")
+	workers := flag.Int("workers", 0, "parallel games (default: number of CPUs)")
+	format := flag.String("format", "text", "output format (text, csv, json)")
+	confidence := flag.Float64("z", 1.96, "normal quantile of the confidence intervals")
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		fmt.Fprintln(os.Stderr, "usage: swcgmatchup [flags] deck deck...")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	config := swcg.MatchupConfig{Games: *games, Seed: *seed, Workers: *workers, Z: *confidence}
+	switch *ai {
+	case "greedy": config.AI = swcg.NewGreedyPlayer
+	case "random": config.AI = swcg.NewRandomPlayer
+	case "mcts":   config.AI = swcg.MCTSPlayerFactory(swcg.MCTSConfig{Iterations: *iterations, Workers: 1})
+	default:
+		fmt.Fprintln(os.Stderr, "unknown AI: "+*ai)
+		os.Exit(2)
+	}
+
+	_, cache := swcg.AnalyzeDB(swcg.CreateDB())
+	names := make([]string, 0, flag.NArg())
+	decks := make([]*swcg.Deck, 0, flag.NArg())
+	for _, arg := range flag.Args() {
+		def, err := swcg.ParseDeckDefinition(arg)
+		if err == nil {
+			var deck *swcg.Deck
+			if deck, err = def.Build(cache); err == nil {
+				if invalid := deck.Validate(); invalid != nil {
+					fmt.Fprintf(os.Stderr, "warning: deck %s: %v\n", def.Name, invalid)
+				}
+				names = append(names, def.Name)
+				decks = append(decks, deck)
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	matrix, err := swcg.RunMatchups(names, decks, config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	switch *format {
+	case "csv":  err = matrix.WriteCSV(os.Stdout)
+	case "json": err = matrix.WriteJSON(os.Stdout)
+	default:     fmt.Print(matrix.Print())
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
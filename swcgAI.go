@@ -0,0 +1,243 @@
+package swcg
+
+import "fmt"
+import "math/rand/v2"
+
+// Players --------------------------------------------------------------------
+
+// Picks the next move of a game among its legal moves.
+type Player interface {
+	Name() string
+	ChooseMove(g *Game, moves []Move) Move
+}
+
+// Creates the player of one game, the seed being derived from the game's.
+type PlayerFactory func(seed uint64) Player
+
+type RandomPlayer struct {
+	rng *rand.Rand
+}
+
+func NewRandomPlayer(seed uint64) Player {
+	return &RandomPlayer{rng: rand.New(rand.NewPCG(seed, 0x72616e64))}
+}
+func (p *RandomPlayer) Name() string { return "Random" }
+func (p *RandomPlayer) ChooseMove(g *Game, moves []Move) Move {
+	return moves[p.rng.IntN(len(moves))]
+}
+
+// Greedy Player --------------------------------------------------------------
+
+// Deploys the most cost efficient cards first, engages the weakest enemy
+// objective with every unit able to strike, defends when it reduces the
+// damage expected from the engagement and commits edge cards by Force icon
+// value while the opponent's stack may be stronger.
+type GreedyPlayer struct{}
+
+func NewGreedyPlayer(uint64) Player { return &GreedyPlayer{} }
+func (p *GreedyPlayer) Name() string { return "Greedy" }
+
+func (p *GreedyPlayer) ChooseMove(g *Game, moves []Move) Move {
+	best, bestScore, found := moves[0], 0.0, false
+	for _, m := range moves {
+		if m.Type == Move_Pass {
+			best, found = m, true
+		}
+	}
+	for _, m := range moves {
+		if score := p.score(g, m); !found || score > bestScore {
+			best, bestScore, found = m, score, true
+		}
+	}
+	return best
+}
+
+// Value of the move, passing being worth 0.
+func (p *GreedyPlayer) score(g *Game, m Move) float64 {
+	switch m.Type {
+	case Move_PlayCard:
+		ci := g.Card(m.Card)
+		if ci.IsType(CardType_Event) {
+			if g.Step != Step_Deploy {
+				return -1
+			}
+			return 1
+		}
+		value := CardValue(ci.Card)
+		if m.Target != NoCard {
+			value += CardValue(g.Card(m.Target).Card) / 10
+		}
+		return 1 + value/float64(max(1, g.CardCost(m.Player, ci.Card)))
+	case Move_UseAbility:
+		return 1
+	case Move_Engage:
+		ci := g.Card(m.Target)
+		return 100 - float64(ci.Card.Health-ci.Damage)
+	case Move_CommitAttacker:
+		return unitStrikeValue(g.Card(m.Card).Card)
+	case Move_CommitDefender:
+		return p.defenseGain(g, m.Card)
+	case Move_EdgeCard:
+		return p.edgeCardValue(g, m)
+	case Move_CommitToForce:
+		return float64(g.Card(m.Card).Card.ForceIcons)
+	}
+	return 0
+}
+
+// Rough value of a card in play: its combat icons (edge enabled ones for
+// half), health, resources and Force icons.
+func CardValue(c *Card) float64 {
+	value := unitStrikeValue(c) + float64(c.Health)/2 + float64(c.Ressources) + float64(c.ForceIcons)/2
+	for _, ability := range c.Abilities {
+		if a, ok := ability.(*CardAbility); ok {
+			for _, e := range a.Effects {
+				value += float64(e.Icons.CombatDamage[0]+e.Icons.Tactics[0]+e.Icons.BlastDamage[0]) + 0.5
+			}
+		}
+	}
+	return value
+}
+
+func unitStrikeValue(c *Card) float64 {
+	if c.CardCombatIcons == nil {
+		return 0
+	}
+	icons := c.CardCombatIcons
+	return float64(icons.CombatDamage[0]+icons.Tactics[0]+icons.BlastDamage[0]) +
+		float64(icons.CombatDamage[1]+icons.Tactics[1]+icons.BlastDamage[1])/2
+}
+
+// Expected objective damage and unit losses saved by adding the unit to
+// the defenders, averaged over both edge battle outcomes.
+func (p *GreedyPlayer) defenseGain(g *Game, unit int) float64 {
+	e := g.Engagement
+	attackers := g.combatUnits(e.Attackers)
+	expectedLoss := func(defenders []CombatUnit) float64 {
+		report := ResolveCombat(attackers, defenders)
+		loss := 0.0
+		for _, outcome := range []*CombatOutcome{report.EdgeWon, report.EdgeLost} {
+			objective := g.Card(e.Objective)
+			loss += float64(outcome.ObjectiveDamage)
+			if outcome.ObjectiveDamage >= objective.Card.Health-objective.Damage {
+				loss += 10
+			}
+			for _, u := range outcome.Defenders() {
+				if u.Destroyed {
+					loss += float64(u.Unit.Card.Cost)
+				}
+			}
+			for _, u := range outcome.Attackers() {
+				if u.Destroyed {
+					loss -= float64(u.Unit.Card.Cost)
+				}
+			}
+		}
+		return loss / 2
+	}
+	defenders := g.combatUnits(e.Defenders)
+	return expectedLoss(defenders) - expectedLoss(append(defenders, g.combatUnits([]int{unit})...))
+}
+
+// Commits the card with the most Force icons (the least valuable to deploy
+// on ties) while our stack doesn't beat one icon per opponent card.
+func (p *GreedyPlayer) edgeCardValue(g *Game, m Move) float64 {
+	c := g.Card(m.Card).Card
+	if c.ForceIcons == 0 && c.Type.GetType() != CardType_Fate {
+		return -1
+	}
+	ours := 0
+	for _, id := range g.Players[m.Player].EdgeStack {
+		ours += g.Card(id).Card.ForceIcons
+	}
+	for _, id := range g.participatingUnits(m.Player) {
+		ours += EdgeKeywordValue(g.Card(id).Card)
+	}
+	opponent := g.Opponent(m.Player)
+	theirs := len(g.Players[opponent].EdgeStack)
+	for _, id := range g.participatingUnits(opponent) {
+		theirs += EdgeKeywordValue(g.Card(id).Card)
+	}
+	if ours > theirs || ours == theirs && m.Player == g.Opponent(g.Active) {
+		return -1
+	}
+	return 1 + float64(c.ForceIcons) - CardValue(c)/10
+}
+
+// Headless Games -------------------------------------------------------------
+
+// Plays a full game, players[0] with the dark side deck.
+func PlayGame(dark, light *Deck, players [2]Player, seed uint64) (*Game, error) {
+	g := NewGame(dark, light, seed)
+	return g, playGame(g, players, nil)
+}
+
+// Plays the game to its end, logging the moves when log isn't nil.
+func playGame(g *Game, players [2]Player, log *ReplayWriter) error {
+	for !g.IsOver() {
+		moves := g.LegalMoves()
+		if len(moves) == 0 {
+			return fmt.Errorf("no legal move during %s/%s", PhaseNames[g.Phase], StepNames[g.Step])
+		}
+		player := g.ToAct()
+		m := players[player].ChooseMove(g, moves)
+		if log != nil {
+			if err := log.WriteMove(g, m); err != nil {
+				return err
+			}
+		}
+		if err := g.Apply(m); err != nil {
+			return fmt.Errorf("%s player: %v", players[player].Name(), err)
+		}
+	}
+	return nil
+}
+
+type MatchResult struct {
+	Games    int
+	Wins     [2]int // by deck
+	DarkWins int
+	Turns    Histogram
+}
+
+func (r *MatchResult) WinRate(deck int) float64 {
+	if r.Games == 0 {
+		return 0
+	}
+	return float64(r.Wins[deck]) / float64(r.Games)
+}
+
+// Plays the decks against each other, swapping sides every game: deck 0
+// plays the dark side in even games.
+func PlayMatch(decks [2]*Deck, players [2]PlayerFactory, games int, seed uint64) (*MatchResult, error) {
+	result := &MatchResult{}
+	for game := 0; game < games; game++ {
+		winner, turns, err := playMatchGame(decks, players, game, seed)
+		if err != nil {
+			return result, err
+		}
+		result.Games++
+		result.Turns.Add(turns)
+		result.Wins[winner]++
+		if winner == game%2 {
+			result.DarkWins++
+		}
+	}
+	return result, nil
+}
+
+// Plays one game of a match and returns the winning deck and the number of
+// turns played.
+func playMatchGame(decks [2]*Deck, players [2]PlayerFactory, game int, seed uint64) (int, int, error) {
+	gameSeed := seed + uint64(game)
+	dark := game % 2
+	light := 1 - dark
+	g, err := PlayGame(decks[dark], decks[light], [2]Player{players[dark](gameSeed << 1), players[light](gameSeed<<1 | 1)}, gameSeed)
+	if err != nil {
+		return 0, 0, err
+	}
+	if g.Winner == Player_Dark {
+		return dark, g.Turn, nil
+	}
+	return light, g.Turn, nil
+}
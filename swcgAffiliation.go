@@ -0,0 +1,187 @@
+package swcg
+
+import "strconv"
+
+// Affiliations ---------------------------------------------------------------
+
+// The affiliation card a deck is built around. It starts the game in play,
+// provides resources of its faction and decides which faction-only objective
+// sets the deck can use.
+type Affiliation struct {
+	Name       string
+	Faction    CardFaction
+	Ressources int
+	Abilities  AbilityList
+	Set        CardSetType
+}
+
+func CreateAffiliations() []Affiliation {
+	return []Affiliation{
+		Affiliation{ Name: "Jedi",
+			Faction: Faction_Jedi,
+			Ressources: 1,
+			Abilities: AbilityList{
+				Reaction("After you win a Force struggle, remove 1 focus token from a target friendly unit.", SynergyList{TypeSynergy(CardType_Unit, true)})},
+			Set: CardSet_Core},
+
+		Affiliation{ Name: "Rebel Alliance",
+			Faction: Faction_RebelAliance,
+			Ressources: 1,
+			Abilities: AbilityList{
+				Reaction("After you win a Force struggle, deal 1 damage to a target enemy unit.", SynergyList{TypeSynergy(CardType_Unit, false)})},
+			Set: CardSet_Core},
+
+		Affiliation{ Name: "Smugglers and Spies",
+			Faction: Faction_Smugglers,
+			Ressources: 1,
+			Abilities: AbilityList{
+				Reaction("After you win a Force struggle, draw 1 card.", nil)},
+			Set: CardSet_Core},
+
+		Affiliation{ Name: "Sith",
+			Faction: Faction_Sith,
+			Ressources: 1,
+			Abilities: AbilityList{
+				Reaction("After you win a Force struggle, deal 1 damage to a target unit or objective.", SynergyList{TypeSynergy(CardType_Unit, false), TypeSynergy(CardType_Objective, false)})},
+			Set: CardSet_Core},
+
+		Affiliation{ Name: "Imperial Navy",
+			Faction: Faction_ImperialNavy,
+			Ressources: 1,
+			Abilities: AbilityList{
+				Reaction("After you win a Force struggle, place 1 focus token on a target enemy unit.", SynergyList{TypeSynergy(CardType_Unit, false)})},
+			Set: CardSet_Core},
+
+		Affiliation{ Name: "Scum and Villainy",
+			Faction: Faction_ScumAndVillany,
+			Ressources: 1,
+			Abilities: AbilityList{
+				Reaction("After you win a Force struggle, gain 1 resource this turn.", nil)},
+			Set: CardSet_Core},
+	}
+}
+
+// Returns nil for the neutral factions, they have no affiliation.
+func AffiliationFor(faction CardFaction) *Affiliation {
+	for _, a := range CreateAffiliations() {
+		if a.Faction == faction {
+			affiliation := a
+			return &affiliation
+		}
+	}
+	return nil
+}
+
+// Deck Affiliation -----------------------------------------------------------
+
+// The deck takes the faction of the affiliation, nil removes the affiliation
+// and keeps the faction.
+func (d *Deck) SetAffiliation(a *Affiliation) {
+	d.Affiliation = a
+	if a != nil {
+		d.Faction = a.Faction
+	}
+}
+
+// Faction Affinity -----------------------------------------------------------
+
+type FactionAffinity int
+const (
+	Affinity_InFaction    FactionAffinity = iota
+	Affinity_Neutral      FactionAffinity = iota
+	Affinity_OutOfFaction FactionAffinity = iota
+	Affinity_MAX          FactionAffinity = iota
+)
+var AffinityNames [Affinity_MAX]string = [Affinity_MAX]string {
+	"InFaction",
+	"Neutral",
+	"OutOfFaction",
+}
+
+func (d *Deck) CardAffinity(c *Card) FactionAffinity {
+	if c.Faction == d.Faction {
+		return Affinity_InFaction
+	} else if c.Faction.IsNeutral() {
+		return Affinity_Neutral
+	}
+	return Affinity_OutOfFaction
+}
+
+// Sets take the affinity of their objective card.
+func (d *Deck) SetAffinity(set *ObjectiveSetDB) FactionAffinity {
+	if set[0] == nil {
+		return Affinity_Neutral
+	}
+	return d.CardAffinity(set[0])
+}
+
+// Faction Analysis -----------------------------------------------------------
+
+// To play a non neutral card, at least one of the resources spent must come
+// from a card of the same faction. The affiliation only ever matches
+// in-faction cards, so out-of-faction cards depend on resources brought by
+// their own faction's objectives and cards.
+type FactionAnalysis struct {
+	SetAffinity        map[int]FactionAffinity
+	InFactionSets      []int
+	NeutralSets        []int
+	OutOfFactionSets   []int
+	ResourcesByFaction map[CardFaction]int
+	Flags              []LintFinding
+}
+
+func (d *Deck) AnalyzeFactions() *FactionAnalysis {
+	analysis := &FactionAnalysis{
+		SetAffinity:        make(map[int]FactionAffinity),
+		InFactionSets:      make([]int, 0),
+		NeutralSets:        make([]int, 0),
+		OutOfFactionSets:   make([]int, 0),
+		ResourcesByFaction: make(map[CardFaction]int),
+		Flags:              make([]LintFinding, 0),
+	}
+
+	if d.Affiliation != nil {
+		analysis.ResourcesByFaction[d.Affiliation.Faction] += d.Affiliation.Ressources
+	}
+	for i, set := range d.Sets {
+		setId := d.SetIds[i]
+		if _, done := analysis.SetAffinity[setId]; !done {
+			affinity := d.SetAffinity(set)
+			analysis.SetAffinity[setId] = affinity
+			switch affinity {
+			case Affinity_InFaction:    analysis.InFactionSets    = append(analysis.InFactionSets, setId)
+			case Affinity_Neutral:      analysis.NeutralSets      = append(analysis.NeutralSets, setId)
+			case Affinity_OutOfFaction: analysis.OutOfFactionSets = append(analysis.OutOfFactionSets, setId)
+			}
+		}
+		for _, c := range set {
+			if c != nil && c.Ressources > 0 {
+				analysis.ResourcesByFaction[c.Faction] += c.Ressources
+			}
+		}
+	}
+
+	flagged := make(map[int]bool)
+	for i, set := range d.Sets {
+		for _, c := range set[1:] {
+			if c == nil || flagged[c.Number] || c.Cost <= 0 || d.CardAffinity(c) != Affinity_OutOfFaction {
+				continue
+			}
+			flagged[c.Number] = true
+
+			matching := analysis.ResourcesByFaction[c.Faction]
+			if matching == 0 {
+				analysis.Flags = append(analysis.Flags, LintFinding{
+					Rule: "NoResourceMatch", Severity: Severity_Error, CardNumber: c.Number, SetId: d.SetIds[i],
+					Message: c.Name+" can never be played: no "+FactionNames[c.Faction]+" resource in the deck, the "+
+						FactionNames[d.Faction]+" affiliation doesn't match it"})
+			} else {
+				analysis.Flags = append(analysis.Flags, LintFinding{
+					Rule: "ResourceMatchPenalty", Severity: Severity_Warning, CardNumber: c.Number, SetId: d.SetIds[i],
+					Message: c.Name+" (cost "+strconv.Itoa(c.Cost)+") needs 1 of the deck's "+strconv.Itoa(matching)+
+						" "+FactionNames[c.Faction]+" resource(s), the "+FactionNames[d.Faction]+" affiliation doesn't match it"})
+			}
+		}
+	}
+	return analysis
+}
@@ -0,0 +1,20 @@
+package swcg
+
+import "testing"
+
+func TestSetAffiliation(t *testing.T) {
+	d := &Deck{Faction: Faction_Jedi, Affiliation: AffiliationFor(Faction_Jedi)}
+	d.SetAffiliation(AffiliationFor(Faction_Smugglers))
+	if d.Faction != Faction_Smugglers || d.Affiliation.Faction != Faction_Smugglers {
+		t.Errorf("SetAffiliation(Smugglers) gave faction %s and affiliation %s", FactionNames[d.Faction], d.Affiliation.Name)
+	}
+
+	d.SetAffiliation(nil)
+	if d.Affiliation != nil || d.Faction != Faction_Smugglers {
+		t.Errorf("SetAffiliation(nil) gave faction %s and affiliation %v", FactionNames[d.Faction], d.Affiliation)
+	}
+	report, ok := d.Validate().(*ValidationReport)
+	if !ok || len(report.ByRule(Rule_DeckAffiliation)) != 1 {
+		t.Errorf("a deck without affiliation should fail validation with Rule_DeckAffiliation, got %v", d.Validate())
+	}
+}
@@ -0,0 +1,321 @@
+package swcg
+
+import "fmt"
+import "sort"
+import "strconv"
+import "strings"
+
+// Aggregates -----------------------------------------------------------------
+
+type AggregateFunc int
+const (
+	Agg_Count AggregateFunc = iota
+	Agg_Sum   AggregateFunc = iota
+	Agg_Avg   AggregateFunc = iota
+	Agg_Min   AggregateFunc = iota
+	Agg_Max   AggregateFunc = iota
+	Agg_MAX   AggregateFunc = iota
+)
+var AggregateNames [Agg_MAX]string = [Agg_MAX]string {
+	"count",
+	"sum",
+	"avg",
+	"min",
+	"max",
+}
+
+// Aggregate function applied to a column. Null cells are skipped, a count
+// without column counts the rows.
+type Aggregation struct {
+	Func   AggregateFunc
+	Column string
+}
+
+func Aggregate(f AggregateFunc, column string) Aggregation {
+	return Aggregation{Func: f, Column: column}
+}
+
+// Parses "count", "count:Column" or "func:Column", e.g. "avg:Cost".
+func ParseAggregation(s string) (Aggregation, error) {
+	name, column, _ := strings.Cut(s, ":")
+	f := lookupNameFold(AggregateNames[:], strings.TrimSpace(name))
+	if f < 0 {
+		return Aggregation{}, fmt.Errorf("%s", unknownNameMessage("aggregate function", name, AggregateNames[:]))
+	}
+	a := Aggregate(AggregateFunc(f), strings.TrimSpace(column))
+	if a.Column == "" && a.Func != Agg_Count {
+		return a, fmt.Errorf("%s needs a column, e.g. %s:Cost", name, AggregateNames[f])
+	}
+	return a, nil
+}
+
+// Header of the aggregate column, e.g. "avg(Cost)".
+func (a Aggregation) Name() string {
+	if a.Column == "" {
+		return AggregateNames[a.Func]
+	}
+	return AggregateNames[a.Func] + "(" + a.Column + ")"
+}
+
+// Aggregate of the cells, null when there is none (but for counts).
+func (f AggregateFunc) apply(cells []Data) (Data, error) {
+	values := make([]Data, 0, len(cells))
+	for _, c := range cells {
+		if _, null := c.(NullData); !null {
+			values = append(values, c)
+		}
+	}
+	if f == Agg_Count {
+		return IntData{V: len(values)}, nil
+	}
+	if len(values) == 0 {
+		return NullData{}, nil
+	}
+	switch f {
+	case Agg_Min, Agg_Max:
+		best := values[0]
+		for _, v := range values[1:] {
+			if c := CompareData(v, best); f == Agg_Min && c < 0 || f == Agg_Max && c > 0 {
+				best = v
+			}
+		}
+		return best, nil
+	}
+
+	sum, allInts := 0.0, true
+	for _, v := range values {
+		n, ok := numericData(v)
+		if !ok {
+			return nil, fmt.Errorf("can't %s the non numeric value %q", AggregateNames[f], v.Print())
+		}
+		_, isInt := v.(IntData)
+		allInts = allInts && isInt
+		sum += n
+	}
+	if f == Agg_Avg {
+		return FloatData{V: sum / float64(len(values))}, nil
+	}
+	if allInts {
+		return IntData{V: int(sum)}, nil
+	}
+	return FloatData{V: sum}, nil
+}
+
+// Grouping -------------------------------------------------------------------
+
+// Rows of a DataCollection grouped by the values of some of its columns.
+type DataGroups struct {
+	data    *DataCollection
+	columns []int
+	keys    []DataRow // group key cells, in key order
+	rows    [][]DataRow
+}
+
+func (d *DataCollection) columnIndices(columns []string) ([]int, error) {
+	indices := make([]int, len(columns))
+	for i, col := range columns {
+		if indices[i] = d.ColumnIndex(col); indices[i] < 0 {
+			return nil, fmt.Errorf("%s", unknownNameMessage("column", col, d.HeaderNames()))
+		}
+	}
+	return indices, nil
+}
+
+func (d *DataCollection) HeaderNames() []string {
+	names := make([]string, len(d.header))
+	for i, h := range d.header {
+		names[i] = h.Name
+	}
+	return names
+}
+
+func compareRows(a, b DataRow) int {
+	for i := range a {
+		if c := CompareData(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// Groups the rows having the same values in the columns, groups being
+// ordered by these values.
+func (d *DataCollection) GroupBy(columns ...string) (*DataGroups, error) {
+	indices, err := d.columnIndices(columns)
+	if err != nil {
+		return nil, err
+	}
+	groups := &DataGroups{data: d, columns: indices}
+	byKey := make(map[string]int)
+	for _, row := range d.rows {
+		key := make(DataRow, len(indices))
+		keyText := make([]string, len(indices))
+		for i, index := range indices {
+			key[i] = row[index]
+			keyText[i] = fmt.Sprintf("%T:%s", row[index], row[index].Print())
+		}
+		g, ok := byKey[strings.Join(keyText, "\x00")]
+		if !ok {
+			g = len(groups.keys)
+			byKey[strings.Join(keyText, "\x00")] = g
+			groups.keys = append(groups.keys, key)
+			groups.rows = append(groups.rows, nil)
+		}
+		groups.rows[g] = append(groups.rows[g], row)
+	}
+	order := make([]int, len(groups.keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return compareRows(groups.keys[order[i]], groups.keys[order[j]]) < 0 })
+	keys, rows := make([]DataRow, len(order)), make([][]DataRow, len(order))
+	for i, g := range order {
+		keys[i], rows[i] = groups.keys[g], groups.rows[g]
+	}
+	groups.keys, groups.rows = keys, rows
+	return groups, nil
+}
+
+func (groups *DataGroups) Len() int { return len(groups.keys) }
+
+// One row per group: the group columns followed by the aggregates.
+func (groups *DataGroups) Aggregate(aggregations ...Aggregation) (*DataCollection, error) {
+	headers := make([]string, 0, len(groups.columns)+len(aggregations))
+	for _, index := range groups.columns {
+		headers = append(headers, groups.data.header[index].Name)
+	}
+	columns := make([]int, len(aggregations))
+	for i, a := range aggregations {
+		columns[i] = -1
+		if a.Column != "" {
+			indices, err := groups.data.columnIndices([]string{a.Column})
+			if err != nil {
+				return nil, err
+			}
+			columns[i] = indices[0]
+		}
+		headers = append(headers, a.Name())
+	}
+
+	result := CreateDataCollection(headers...)
+	for g, key := range groups.keys {
+		row := append(DataRow(nil), key...)
+		for i, a := range aggregations {
+			cells := make([]Data, len(groups.rows[g]))
+			for r, dataRow := range groups.rows[g] {
+				cells[r] = IntData{V: 1}
+				if columns[i] >= 0 {
+					cells[r] = dataRow[columns[i]]
+				}
+			}
+			value, err := a.Func.apply(cells)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", a.Name(), err)
+			}
+			row = append(row, value)
+		}
+		result.rows = append(result.rows, row)
+	}
+	return result, nil
+}
+
+// Pivot Tables ---------------------------------------------------------------
+
+// Aggregate of valueCol for every pair of rowCol and colCol values: one row
+// per rowCol value and one column per colCol value, null where no row has
+// both, e.g. Pivot("Faction", "Type", "Cost", Agg_Avg).
+func (d *DataCollection) Pivot(rowCol, colCol, valueCol string, f AggregateFunc) (*DataCollection, error) {
+	groups, err := d.GroupBy(rowCol, colCol)
+	if err != nil {
+		return nil, err
+	}
+	cells, err := groups.Aggregate(Aggregate(f, valueCol))
+	if err != nil {
+		return nil, err
+	}
+
+	columnKeys := make([]Data, 0)
+	for _, row := range cells.rows {
+		found := false
+		for _, k := range columnKeys {
+			found = found || CompareData(k, row[1]) == 0
+		}
+		if !found {
+			columnKeys = append(columnKeys, row[1])
+		}
+	}
+	sort.SliceStable(columnKeys, func(i, j int) bool { return CompareData(columnKeys[i], columnKeys[j]) < 0 })
+
+	headers := []string{d.header[d.ColumnIndex(rowCol)].Name}
+	for _, k := range columnKeys {
+		headers = append(headers, k.Print())
+	}
+	result := CreateDataCollection(headers...)
+	for _, row := range cells.rows {
+		last := len(result.rows) - 1
+		if last < 0 || CompareData(result.rows[last][0], row[0]) != 0 {
+			newRow := DataRow{row[0]}
+			for range columnKeys {
+				newRow = append(newRow, NullData{})
+			}
+			result.rows = append(result.rows, newRow)
+			last++
+		}
+		for i, k := range columnKeys {
+			if CompareData(k, row[1]) == 0 {
+				result.rows[last][i+1] = row[2]
+			}
+		}
+	}
+	return result, nil
+}
+
+// Copy of the collection with one row per element of the list cells of the
+// column (e.g. a card per trait), integers becoming IntData. Rows with an
+// empty list get a null cell, other cells are kept as they are.
+func (d *DataCollection) Explode(column string) (*DataCollection, error) {
+	indices, err := d.columnIndices([]string{column})
+	if err != nil {
+		return nil, err
+	}
+	index := indices[0]
+	result := CreateDataCollection(d.HeaderNames()...)
+	for _, row := range d.rows {
+		list, ok := row[index].(ListData)
+		if !ok {
+			result.rows = append(result.rows, row)
+			continue
+		}
+		elements := make([]Data, 0, len(list.V))
+		for _, s := range list.V {
+			if n, err := strconv.Atoi(s); err == nil {
+				elements = append(elements, IntData{V: n})
+			} else {
+				elements = append(elements, StrData{V: s})
+			}
+		}
+		if len(elements) == 0 {
+			elements = append(elements, NullData{})
+		}
+		for _, e := range elements {
+			newRow := append(DataRow(nil), row...)
+			newRow[index] = e
+			result.rows = append(result.rows, newRow)
+		}
+	}
+	return result, nil
+}
+
+// Rounded to three decimals at most, enough for averages in a table, e.g.
+// 2.9996 -> "3" and 0.125 -> "0.125".
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 3, 64)
+	if !strings.Contains(s, ".") {
+		return s // NaN or infinite
+	}
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
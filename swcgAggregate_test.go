@@ -0,0 +1,120 @@
+package swcg
+
+import "math"
+import "reflect"
+import "testing"
+
+func testAggregateData() *DataCollection {
+	d := CreateDataCollection("Name", "Type", "Side", "Cost", "Traits")
+	d.AddRow("A", "Unit", "Light", 1, []string{"Jedi", "Character"})
+	d.AddRow("B", "Unit", "Dark", 4, []string{"Character"})
+	d.AddRow("C", "Event", "Light", 2, []string{})
+	d.AddRow("D", "Unit", "Dark", nil, []string{"Jedi"})
+	d.AddRow("E", "Event", "Light", 2, []string{"2", "18"})
+	return d
+}
+
+func TestGroupByAggregate(t *testing.T) {
+	groups, err := testAggregateData().GroupBy("type")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := groups.Aggregate(Aggregate(Agg_Count, ""), Aggregate(Agg_Count, "Cost"), Aggregate(Agg_Sum, "Cost"),
+		Aggregate(Agg_Avg, "Cost"), Aggregate(Agg_Min, "Cost"), Aggregate(Agg_Max, "Name"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedHeader := []string{"Type", "count", "count(Cost)", "sum(Cost)", "avg(Cost)", "min(Cost)", "max(Name)"}
+	if header := result.HeaderNames(); !reflect.DeepEqual(header, expectedHeader) {
+		t.Errorf("got header %q, expected %q", header, expectedHeader)
+	}
+	expected := []DataRow{
+		{StrData{V: "Event"}, IntData{V: 2}, IntData{V: 2}, IntData{V: 4}, FloatData{V: 2}, IntData{V: 2}, StrData{V: "E"}},
+		{StrData{V: "Unit"}, IntData{V: 3}, IntData{V: 2}, IntData{V: 5}, FloatData{V: 2.5}, IntData{V: 1}, StrData{V: "D"}},
+	}
+	if !reflect.DeepEqual(result.rows, expected) {
+		t.Errorf("got %v, expected %v", result.rows, expected)
+	}
+}
+
+func TestAggregateErrors(t *testing.T) {
+	d := testAggregateData()
+	if _, err := d.GroupBy("Colour"); err == nil {
+		t.Errorf("grouping by an unknown column should fail")
+	}
+	groups, _ := d.GroupBy("Type")
+	if _, err := groups.Aggregate(Aggregate(Agg_Sum, "Name")); err == nil {
+		t.Errorf("summing strings should fail")
+	}
+	if _, err := ParseAggregation("avg"); err == nil {
+		t.Errorf("avg without a column should fail")
+	}
+	if a, err := ParseAggregation("MAX:Cost"); err != nil || a != Aggregate(Agg_Max, "Cost") {
+		t.Errorf("got %v, %v", a, err)
+	}
+}
+
+func TestPivot(t *testing.T) {
+	result, err := testAggregateData().Pivot("Type", "Side", "Cost", Agg_Sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header := result.HeaderNames(); !reflect.DeepEqual(header, []string{"Type", "Dark", "Light"}) {
+		t.Errorf("got header %q", header)
+	}
+	expected := []DataRow{
+		{StrData{V: "Event"}, NullData{}, IntData{V: 4}},
+		{StrData{V: "Unit"}, IntData{V: 4}, IntData{V: 1}},
+	}
+	if !reflect.DeepEqual(result.rows, expected) {
+		t.Errorf("got %v, expected %v", result.rows, expected)
+	}
+}
+
+func TestExplode(t *testing.T) {
+	result, err := testAggregateData().Explode("Traits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := columnValues(result, 0); !reflect.DeepEqual(names, []string{"A", "A", "B", "C", "D", "E", "E"}) {
+		t.Errorf("got names %q", names)
+	}
+	expected := []Data{StrData{V: "Jedi"}, StrData{V: "Character"}, StrData{V: "Character"}, NullData{},
+		StrData{V: "Jedi"}, IntData{V: 2}, IntData{V: 18}}
+	for i, row := range result.rows {
+		if row[4] != expected[i] {
+			t.Errorf("row %d: got %#v, expected %#v", i, row[4], expected[i])
+		}
+	}
+	if _, err := testAggregateData().Explode("Colour"); err == nil {
+		t.Errorf("exploding an unknown column should fail")
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	tests := []struct {
+		f        float64
+		expected string
+	}{
+		{2.5, "2.5"}, {0.125, "0.125"}, {1.0 / 3, "0.333"}, {100, "100"}, {-2.25, "-2.25"},
+		{2.9996, "3"}, {0.0004, "0"}, {-1.9999, "-2"}, {-0.0004, "0"}, {10.0501, "10.05"},
+		{math.NaN(), "NaN"}, {math.Inf(1), "+Inf"},
+	}
+	for _, test := range tests {
+		if s := formatFloat(test.f); s != test.expected {
+			t.Errorf("formatFloat(%v) = %q, expected %q", test.f, s, test.expected)
+		}
+	}
+}
+
+func TestFloatsAreOnlyRoundedInTables(t *testing.T) {
+	third := FloatData{V: 1.0 / 3}
+	if third.Print() != "0.3333333333333333" {
+		t.Errorf("FloatData should print its exact value, got %q", third.Print())
+	}
+	d := CreateDataCollection("Value")
+	d.AddRow(third.V)
+	if out := d.Print(); out != "Value\n0.333\n" {
+		t.Errorf("got table %q", out)
+	}
+}
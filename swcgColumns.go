@@ -0,0 +1,121 @@
+package swcg
+
+import "fmt"
+import "strconv"
+import "strings"
+
+// Card Columns ---------------------------------------------------------------
+//
+// Registry of the card attributes a DataCollection of cards can show, as
+// values DataCollection.AddRow accepts. Names sort case insensitively and
+// lists (traits, keywords) as ListData.
+
+type CardColumn struct {
+	Name  string
+	Value func(c *Card) interface{}
+}
+
+func cardTraitNames(c *Card) []string {
+	names := make([]string, 0)
+	for _, ability := range c.Abilities {
+		if trait, ok := ability.(*CardTrait); ok {
+			names = append(names, TraitNames[trait.Trait])
+		}
+	}
+	return names
+}
+
+func cardKeywordNames(c *Card) []string {
+	names := make([]string, 0)
+	for _, ability := range c.Abilities {
+		if keyword, ok := ability.(KeywordInterface); ok {
+			names = append(names, KeywordNames[keyword.GetKeyword()])
+		}
+	}
+	return names
+}
+
+func cardTypeName(c *Card) interface{} {
+	if c.Type == nil {
+		return nil
+	}
+	return CardTypeNames[c.Type.GetType()]
+}
+
+var CardColumns = []CardColumn{
+	{"Number",    func(c *Card) interface{} { return c.Number }},
+	{"Name",      func(c *Card) interface{} { return FoldStrData{V: c.Name} }},
+	{"Faction",   func(c *Card) interface{} { return FactionNames[c.Faction] }},
+	{"Type",      func(c *Card) interface{} { return cardTypeName(c) }},
+	{"Cost",      func(c *Card) interface{} { return c.Cost }},
+	{"Resources", func(c *Card) interface{} { return c.Ressources }},
+	{"Force",     func(c *Card) interface{} { return c.ForceIcons }},
+	{"Health",    func(c *Card) interface{} { return c.Health }},
+	{"Combat",    func(c *Card) interface{} {
+		if c.CardCombatIcons == nil {
+			return nil
+		}
+		i := c.CardCombatIcons
+		return strconv.Itoa(i.CombatDamage[0])+"/"+strconv.Itoa(i.CombatDamage[1])+" "+
+			strconv.Itoa(i.Tactics[0])+"/"+strconv.Itoa(i.Tactics[1])+" "+
+			strconv.Itoa(i.BlastDamage[0])+"/"+strconv.Itoa(i.BlastDamage[1])
+	}},
+	{"Traits",    func(c *Card) interface{} { return cardTraitNames(c) }},
+	{"Keywords",  func(c *Card) interface{} { return cardKeywordNames(c) }},
+	{"Sets",      func(c *Card) interface{} {
+		ids := make([]string, 0, len(c.ObjectiveSets))
+		for _, s := range c.ObjectiveSets {
+			ids = append(ids, strconv.Itoa(s.SetId))
+		}
+		return ids
+	}},
+}
+
+var DefaultCardColumns = []string{"Number", "Name", "Faction", "Type", "Cost", "Force", "Sets"}
+
+func CardColumnNames() []string {
+	names := make([]string, len(CardColumns))
+	for i, col := range CardColumns {
+		names[i] = col.Name
+	}
+	return names
+}
+
+// Case insensitive lookup in CardColumns.
+func CardColumnByName(name string) (CardColumn, bool) {
+	for _, col := range CardColumns {
+		if strings.EqualFold(col.Name, name) {
+			return col, true
+		}
+	}
+	return CardColumn{}, false
+}
+
+func CardColumnsByName(names ...string) ([]CardColumn, error) {
+	columns := make([]CardColumn, 0, len(names))
+	for _, name := range names {
+		col, ok := CardColumnByName(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("%s", unknownNameMessage("column", name, CardColumnNames()))
+		}
+		columns = append(columns, col)
+	}
+	return columns, nil
+}
+
+// One row per card with the given columns.
+func CardDataCollection(cards []*Card, columns []CardColumn) *DataCollection {
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = col.Name
+	}
+	data := CreateDataCollection(names...)
+	for _, c := range cards {
+		row := make([]interface{}, len(columns))
+		for i, col := range columns {
+			row[i] = col.Value(c)
+		}
+		data.AddRow(row...)
+	}
+	return data
+}
@@ -0,0 +1,345 @@
+package swcg
+
+import "sort"
+
+// Combat Units ---------------------------------------------------------------
+
+// Icon and health bonuses given to a unit, e.g. by its enhancements.
+type CombatModifier struct {
+	CombatDamage   CombatIcon
+	Tactics        CombatIcon
+	BlastDamage    CombatIcon
+	Health         int
+	TargetedStrike bool
+}
+
+// A unit participating in an engagement, with the tokens it already has.
+type CombatUnit struct {
+	Card      *Card
+	Damage    int
+	Focus     int
+	Shields   int
+	Modifiers []CombatModifier
+}
+
+// Icon value of the unit, the edge-enabled part only counting when its side
+// won the edge battle.
+func (u *CombatUnit) iconValue(icon func(*CardCombatIcons) CombatIcon, modifier func(*CombatModifier) CombatIcon, edgeWon bool) int {
+	total := CombatIcon{}
+	if u.Card.CardCombatIcons != nil {
+		total = icon(u.Card.CardCombatIcons)
+	}
+	for i := range u.Modifiers {
+		m := modifier(&u.Modifiers[i])
+		total[0] += m[0]
+		total[1] += m[1]
+	}
+	if edgeWon {
+		return total[0] + total[1]
+	}
+	return total[0]
+}
+func (u *CombatUnit) CombatDamage(edgeWon bool) int {
+	return u.iconValue(func(i *CardCombatIcons) CombatIcon { return i.CombatDamage }, func(m *CombatModifier) CombatIcon { return m.CombatDamage }, edgeWon)
+}
+func (u *CombatUnit) Tactics(edgeWon bool) int {
+	return u.iconValue(func(i *CardCombatIcons) CombatIcon { return i.Tactics }, func(m *CombatModifier) CombatIcon { return m.Tactics }, edgeWon)
+}
+func (u *CombatUnit) BlastDamage(edgeWon bool) int {
+	return u.iconValue(func(i *CardCombatIcons) CombatIcon { return i.BlastDamage }, func(m *CombatModifier) CombatIcon { return m.BlastDamage }, edgeWon)
+}
+func (u *CombatUnit) HasTargetedStrike() bool {
+	for _, m := range u.Modifiers {
+		if m.TargetedStrike {
+			return true
+		}
+	}
+	return KeywordPredicate(K_TargetedStrike)(u.Card)
+}
+func (u *CombatUnit) Health() int {
+	health := u.Card.Health
+	for _, m := range u.Modifiers {
+		health += m.Health
+	}
+	return health
+}
+
+// Combat Resolution ----------------------------------------------------------
+
+type CombatUnitOutcome struct {
+	Unit         *CombatUnit
+	Struck       bool
+	DamageTaken  int // damage dealt during this combat, after shields
+	ShieldsUsed  int
+	ShieldGained bool // placed by the Shielding keyword
+	FocusTaken   int  // focus tokens placed by enemy tactics
+	Destroyed    bool
+}
+
+func (o *CombatUnitOutcome) remainingHealth() int {
+	return o.Unit.Health() - o.Unit.Damage - o.DamageTaken
+}
+func (o *CombatUnitOutcome) remainingShields() int {
+	shields := o.Unit.Shields - o.ShieldsUsed
+	if o.ShieldGained {
+		shields++
+	}
+	return shields
+}
+func (o *CombatUnitOutcome) canStrike() bool {
+	return !o.Destroyed && !o.Struck && o.Unit.Focus+o.FocusTaken == 0
+}
+
+type CombatOutcome struct {
+	EdgeWinner      int
+	Sides           [2][]*CombatUnitOutcome // indexed by Edge_Attacker / Edge_Defender
+	ObjectiveDamage int
+	Unopposed       bool
+}
+
+func (o *CombatOutcome) Attackers() []*CombatUnitOutcome { return o.Sides[Edge_Attacker] }
+func (o *CombatOutcome) Defenders() []*CombatUnitOutcome { return o.Sides[Edge_Defender] }
+func (o *CombatOutcome) DamageDealtBy(side int) int {
+	damage := 0
+	for _, u := range o.Sides[1-side] {
+		damage += u.DamageTaken
+	}
+	return damage
+}
+func (o *CombatOutcome) FocusPlacedBy(side int) int {
+	focus := 0
+	for _, u := range o.Sides[1-side] {
+		focus += u.FocusTaken
+	}
+	return focus
+}
+func (o *CombatOutcome) UnitsDestroyed(side int) int {
+	destroyed := 0
+	for _, u := range o.Sides[side] {
+		if u.Destroyed {
+			destroyed++
+		}
+	}
+	return destroyed
+}
+
+type CombatReport struct {
+	EdgeWon  *CombatOutcome // the attacker won the edge battle
+	EdgeLost *CombatOutcome // the defender won the edge battle
+}
+
+// Resolves the engagement for both edge battle outcomes.
+func ResolveCombat(attackers, defenders []CombatUnit) *CombatReport {
+	return &CombatReport{
+		EdgeWon:  ResolveCombatWithEdge(attackers, defenders, Edge_Attacker),
+		EdgeLost: ResolveCombatWithEdge(attackers, defenders, Edge_Defender),
+	}
+}
+
+// Resolves the strikes of an engagement. Each Shielding unit of the edge
+// winner shields a friendly unit without shield (the one the strongest
+// enemy strike would target), the edge winner strikes first and its units'
+// edge-enabled icons are active, then both sides alternate until every
+// unit has struck. A unit can't strike once it has focus tokens (including
+// those placed by enemy tactics) and is destroyed once its damage reaches
+// its health.
+//
+// Targets are chosen greedily: combat damage goes to the enemy unit it can
+// destroy (the most expensive one), otherwise to the weakest one, and is
+// split between several units for Targeted Strike units. Enemy Protect
+// units take damage meant to the units they protect when they survive it,
+// or when they are cheaper. Shields prevent 1 damage each. Tactics focus
+// the enemy units that haven't struck yet, strongest first, and the
+// attacker's blast damage goes to the engaged objective, with 1 more damage
+// if the engagement ends unopposed.
+func ResolveCombatWithEdge(attackers, defenders []CombatUnit, edgeWinner int) *CombatOutcome {
+	outcome := &CombatOutcome{EdgeWinner: edgeWinner}
+	for side, units := range [2][]CombatUnit{attackers, defenders} {
+		outcome.Sides[side] = make([]*CombatUnitOutcome, len(units))
+		for i := range units {
+			outcome.Sides[side][i] = &CombatUnitOutcome{Unit: &units[i]}
+		}
+	}
+
+	// Shielding: each of the edge winner's Shielding units shields a friendly
+	// unit without shield, the one the strongest enemy strike would target
+	enemyDamage := 0
+	for _, u := range outcome.Sides[1-edgeWinner] {
+		enemyDamage = max(enemyDamage, u.Unit.CombatDamage(false))
+	}
+	for _, shielding := range outcome.Sides[edgeWinner] {
+		if !KeywordPredicate(K_Shielding)(shielding.Unit.Card) {
+			continue
+		}
+		unshielded := make([]*CombatUnitOutcome, 0)
+		for _, u := range outcome.Sides[edgeWinner] {
+			if u.remainingShields() == 0 {
+				unshielded = append(unshielded, u)
+			}
+		}
+		if target := damageTarget(unshielded, enemyDamage); target != nil {
+			target.ShieldGained = true
+		}
+	}
+
+	side := edgeWinner
+	for {
+		striker := nextStriker(outcome.Sides[side], side == edgeWinner)
+		if striker == nil {
+			side = 1 - side
+			if striker = nextStriker(outcome.Sides[side], side == edgeWinner); striker == nil {
+				break
+			}
+		}
+		strike(outcome, striker, side, side == edgeWinner)
+		side = 1 - side
+	}
+
+	attackerStanding, defenderStanding := false, false
+	for _, u := range outcome.Attackers() {
+		attackerStanding = attackerStanding || !u.Destroyed
+	}
+	for _, u := range outcome.Defenders() {
+		defenderStanding = defenderStanding || !u.Destroyed
+	}
+	if attackerStanding && !defenderStanding {
+		outcome.Unopposed = true
+		outcome.ObjectiveDamage++
+	}
+	return outcome
+}
+
+func strikeValue(u *CombatUnitOutcome, edgeWon bool) int {
+	return u.Unit.CombatDamage(edgeWon) + u.Unit.Tactics(edgeWon) + u.Unit.BlastDamage(edgeWon)
+}
+
+func nextStriker(units []*CombatUnitOutcome, edgeWon bool) *CombatUnitOutcome {
+	var best *CombatUnitOutcome
+	for _, u := range units {
+		if u.canStrike() && (best == nil || strikeValue(u, edgeWon) > strikeValue(best, edgeWon)) {
+			best = u
+		}
+	}
+	return best
+}
+
+func strike(outcome *CombatOutcome, striker *CombatUnitOutcome, side int, edgeWon bool) {
+	striker.Struck = true
+	enemies := outcome.Sides[1-side]
+	enemyEdgeWon := !edgeWon
+
+	if damage := striker.Unit.CombatDamage(edgeWon); damage > 0 {
+		if striker.Unit.HasTargetedStrike() {
+			splitDamage(enemies, damage)
+		} else if target := damageTarget(enemies, damage); target != nil {
+			dealDamage(enemies, target, damage)
+		}
+	}
+
+	for tactics := striker.Unit.Tactics(edgeWon); tactics > 0; tactics-- {
+		var target *CombatUnitOutcome
+		for _, u := range enemies {
+			if u.canStrike() && (target == nil || strikeValue(u, enemyEdgeWon) > strikeValue(target, enemyEdgeWon)) {
+				target = u
+			}
+		}
+		if target == nil {
+			for _, u := range enemies {
+				if !u.Destroyed && (target == nil || u.FocusTaken < target.FocusTaken) {
+					target = u
+				}
+			}
+		}
+		if target == nil {
+			break
+		}
+		target.FocusTaken++
+	}
+
+	if side == Edge_Attacker {
+		outcome.ObjectiveDamage += striker.Unit.BlastDamage(edgeWon)
+	}
+}
+
+func effectiveHealth(u *CombatUnitOutcome) int {
+	return u.remainingHealth() + u.remainingShields()
+}
+
+func damageTarget(enemies []*CombatUnitOutcome, damage int) *CombatUnitOutcome {
+	var killable, weakest *CombatUnitOutcome
+	for _, u := range enemies {
+		if u.Destroyed {
+			continue
+		}
+		if effectiveHealth(u) <= damage && (killable == nil || u.Unit.Card.Cost > killable.Unit.Card.Cost) {
+			killable = u
+		}
+		if weakest == nil || effectiveHealth(u) < effectiveHealth(weakest) {
+			weakest = u
+		}
+	}
+	if killable != nil {
+		return killable
+	}
+	return weakest
+}
+
+// Kills as many units as possible, weakest first, the rest of the damage
+// going to the weakest survivor.
+func splitDamage(enemies []*CombatUnitOutcome, damage int) {
+	alive := make([]*CombatUnitOutcome, 0, len(enemies))
+	for _, u := range enemies {
+		if !u.Destroyed {
+			alive = append(alive, u)
+		}
+	}
+	sort.SliceStable(alive, func(i, j int) bool { return effectiveHealth(alive[i]) < effectiveHealth(alive[j]) })
+	for _, u := range alive {
+		if damage <= 0 {
+			return
+		}
+		if h := effectiveHealth(u); h <= damage {
+			dealDamage(enemies, u, h)
+			damage -= h
+		}
+	}
+	if target := damageTarget(enemies, damage); target != nil && damage > 0 {
+		dealDamage(enemies, target, damage)
+	}
+}
+
+func dealDamage(units []*CombatUnitOutcome, target *CombatUnitOutcome, damage int) {
+	if protector := findProtector(units, target, damage); protector != nil {
+		target = protector
+	}
+	if shields := target.remainingShields(); shields > 0 {
+		prevented := shields
+		if prevented > damage {
+			prevented = damage
+		}
+		target.ShieldsUsed += prevented
+		damage -= prevented
+	}
+	target.DamageTaken += damage
+	if target.remainingHealth() <= 0 {
+		target.Destroyed = true
+	}
+}
+
+func findProtector(units []*CombatUnitOutcome, target *CombatUnitOutcome, damage int) *CombatUnitOutcome {
+	for _, u := range units {
+		if u == target || u.Destroyed {
+			continue
+		}
+		for _, ability := range u.Unit.Card.Abilities {
+			protect, ok := ability.(*ProtectKeywordType)
+			if !ok || !TraitPredicate(protect.ProtectedTrait)(target.Unit.Card) {
+				continue
+			}
+			if effectiveHealth(u) > damage || u.Unit.Card.Cost < target.Unit.Card.Cost {
+				return u
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package swcg
+
+import "testing"
+
+func combatUnit(name string, cost, health int, combat, tactics, blast int, abilities ...AbilityInterface) CombatUnit {
+	return CombatUnit{Card: &Card{Name: name, Type: Type(CardType_Unit), Cost: cost, Health: health,
+		CardCombatIcons: CombatIcons(CombatIcon{combat, 0}, CombatIcon{tactics, 0}, CombatIcon{blast, 0}), Abilities: abilities}}
+}
+
+func TestShieldingShieldsTheThreatenedUnit(t *testing.T) {
+	attackers := []CombatUnit{
+		combatUnit("Shielder", 2, 3, 0, 0, 0, Key(K_Shielding)),
+		combatUnit("Target", 3, 1, 0, 0, 0),
+	}
+	defenders := []CombatUnit{combatUnit("Striker", 2, 5, 1, 0, 0)}
+	tests := []struct {
+		edgeWinner int
+		shielded   bool
+	}{
+		{Edge_Attacker, true},
+		{Edge_Defender, false},
+	}
+	for _, test := range tests {
+		outcome := ResolveCombatWithEdge(attackers, defenders, test.edgeWinner)
+		shielder, target := outcome.Attackers()[0], outcome.Attackers()[1]
+		if shielder.ShieldGained || target.ShieldGained != test.shielded || target.Destroyed == test.shielded {
+			t.Errorf("edge winner %d: shielder %+v, target %+v", test.edgeWinner, shielder, target)
+		}
+	}
+
+	shielded := append([]CombatUnit(nil), attackers...)
+	shielded[1].Shields = 1
+	outcome := ResolveCombatWithEdge(shielded, defenders, Edge_Attacker)
+	if outcome.Attackers()[1].ShieldGained || !outcome.Attackers()[0].ShieldGained {
+		t.Errorf("a unit with a shield can't get another one")
+	}
+}
+
+func TestProtectTakesTheDamage(t *testing.T) {
+	attackers := []CombatUnit{combatUnit("Striker", 2, 3, 2, 0, 0)}
+	tests := []struct {
+		name            string
+		protectorHealth int
+		protected       bool
+	}{
+		{"the protector survives", 3, true},
+		{"the protector dies but is cheaper", 2, true},
+	}
+	for _, test := range tests {
+		defenders := []CombatUnit{
+			combatUnit("Protector", 1, test.protectorHealth, 0, 0, 0, KeyProtect(Trait_Character)),
+			combatUnit("Character", 4, 1, 0, 0, 0, Trait(Trait_Character)),
+		}
+		outcome := ResolveCombatWithEdge(attackers, defenders, Edge_Attacker)
+		protector, character := outcome.Defenders()[0], outcome.Defenders()[1]
+		if protector.DamageTaken != 2 || character.DamageTaken != 0 || character.Destroyed {
+			t.Errorf("%s: protector %+v, character %+v", test.name, protector, character)
+		}
+	}
+
+	defenders := []CombatUnit{
+		combatUnit("Protector", 1, 3, 0, 0, 0, KeyProtect(Trait_Character)),
+		combatUnit("Droid", 4, 1, 0, 0, 0, Trait(Trait_Droid)),
+	}
+	if outcome := ResolveCombatWithEdge(attackers, defenders, Edge_Attacker); !outcome.Defenders()[1].Destroyed {
+		t.Errorf("Protect only covers units with the protected trait")
+	}
+}
+
+func TestTacticsFocusTheStrongestEnemy(t *testing.T) {
+	attackers := []CombatUnit{combatUnit("Tactician", 2, 3, 0, 1, 0)}
+	defenders := []CombatUnit{
+		combatUnit("Weak", 1, 3, 1, 0, 0),
+		combatUnit("Strong", 3, 3, 2, 0, 0),
+	}
+	outcome := ResolveCombatWithEdge(attackers, defenders, Edge_Attacker)
+	weak, strong := outcome.Defenders()[0], outcome.Defenders()[1]
+	if strong.FocusTaken != 1 || strong.Struck || !weak.Struck {
+		t.Errorf("weak %+v, strong %+v", weak, strong)
+	}
+	if outcome.FocusPlacedBy(Edge_Attacker) != 1 || outcome.DamageDealtBy(Edge_Defender) != 1 {
+		t.Errorf("focus placed %d, damage taken %d", outcome.FocusPlacedBy(Edge_Attacker), outcome.DamageDealtBy(Edge_Defender))
+	}
+}
+
+func TestBlastDamageAndUnopposed(t *testing.T) {
+	blaster := combatUnit("Blaster", 2, 3, 1, 0, 2)
+	edgeBlaster := CombatUnit{Card: &Card{Name: "Edge Blaster", Type: Type(CardType_Unit), Health: 3,
+		CardCombatIcons: CombatIcons(CombatIcon{0, 0}, CombatIcon{0, 0}, CombatIcon{0, 1})}}
+	tests := []struct {
+		name       string
+		attackers  []CombatUnit
+		defenders  []CombatUnit
+		edgeWinner int
+		damage     int
+		unopposed  bool
+	}{
+		{"no defenders", []CombatUnit{blaster}, nil, Edge_Defender, 3, true},
+		{"defender destroyed", []CombatUnit{blaster}, []CombatUnit{combatUnit("Defender", 1, 1, 0, 0, 0)}, Edge_Attacker, 3, true},
+		{"defender standing", []CombatUnit{blaster}, []CombatUnit{combatUnit("Defender", 1, 2, 0, 0, 0)}, Edge_Attacker, 2, false},
+		{"edge-enabled blast, edge won", []CombatUnit{edgeBlaster}, []CombatUnit{combatUnit("Defender", 1, 2, 0, 0, 0)}, Edge_Attacker, 1, false},
+		{"edge-enabled blast, edge lost", []CombatUnit{edgeBlaster}, []CombatUnit{combatUnit("Defender", 1, 2, 0, 0, 0)}, Edge_Defender, 0, false},
+		{"defenders don't blast", []CombatUnit{combatUnit("Attacker", 1, 2, 0, 0, 0)}, []CombatUnit{blaster}, Edge_Defender, 0, false},
+	}
+	for _, test := range tests {
+		outcome := ResolveCombatWithEdge(test.attackers, test.defenders, test.edgeWinner)
+		if outcome.ObjectiveDamage != test.damage || outcome.Unopposed != test.unopposed {
+			t.Errorf("%s: objective damage %d, unopposed %v, expected %d, %v", test.name, outcome.ObjectiveDamage, outcome.Unopposed, test.damage, test.unopposed)
+		}
+	}
+}
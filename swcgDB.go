@@ -1,8 +1,10 @@
 package swcg
 
 import "fmt"
+import "reflect"
 import "strconv"
 import "sort"
+import "strings"
 
 type CardMap        	map[int]*Card
 type ObjectiveSetDB 	[6]*Card
@@ -12,23 +14,207 @@ type KeywordMap     	map[CardKeywordType][]*Card
 type TraitMap       	map[CardTraitType][]*Card
 type PlayAreaSynergyMap []*Card
 
+// Typed cell of a DataCollection. Compare orders cells of the same type
+// (negative, 0 or positive), CompareData any two cells.
 type Data interface{
 	Print() string
 	IntValue() int
+	Compare(other Data) int
 }
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b: return -1
+	case a > b: return 1
+	}
+	return 0
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b: return -1
+	case a > b: return 1
+	}
+	return 0
+}
+
 type IntData struct {V int}
 func (d IntData) Print() string {return strconv.Itoa(d.V)}
 func (d IntData) IntValue() int {return d.V}
+func (d IntData) Compare(other Data) int {return compareInts(d.V, other.(IntData).V)}
+
+type FloatData struct {V float64}
+func (d FloatData) Print() string {return strconv.FormatFloat(d.V, 'f', -1, 64)}
+func (d FloatData) IntValue() int {return int(d.V)}
+func (d FloatData) Compare(other Data) int {return compareFloats(d.V, other.(FloatData).V)}
+
+type BoolData struct {V bool}
+func (d BoolData) Print() string {return strconv.FormatBool(d.V)}
+func (d BoolData) IntValue() int {
+	if d.V {
+		return 1
+	}
+	return 0
+}
+func (d BoolData) Compare(other Data) int {return compareInts(d.IntValue(), other.IntValue())}
 
+// Lexicographic (byte) ordering.
 type StrData struct {V string}
 func (d StrData) Print() string {return d.V}
-func (d StrData) IntValue() int {return int([]byte(d.V)[0])}
+func (d StrData) IntValue() int {
+	if d.V == "" {
+		return 0
+	}
+	return int(d.V[0])
+}
+func (d StrData) Compare(other Data) int {return strings.Compare(d.V, other.(StrData).V)}
+
+// Case insensitive ordering, ties broken lexicographically.
+type FoldStrData struct {V string}
+func (d FoldStrData) Print() string {return d.V}
+func (d FoldStrData) IntValue() int {return StrData(d).IntValue()}
+func (d FoldStrData) Compare(other Data) int {
+	o := other.(FoldStrData).V
+	if c := strings.Compare(strings.ToLower(d.V), strings.ToLower(o)); c != 0 {
+		return c
+	}
+	return strings.Compare(d.V, o)
+}
+
+// List of strings (e.g. traits or set ids), ordered element by element then
+// by length, integer elements by value ("2" < "18"). Printed comma
+// separated, "-" when empty.
+type ListData struct {V []string}
+func (d ListData) Print() string {
+	if len(d.V) == 0 {
+		return "-"
+	}
+	return strings.Join(d.V, ",")
+}
+func (d ListData) IntValue() int {return len(d.V)}
+func (d ListData) Compare(other Data) int {
+	o := other.(ListData).V
+	for i := 0; i < len(d.V) && i < len(o); i++ {
+		if c := compareListElements(d.V[i], o[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(d.V), len(o))
+}
+
+// Integers first, by value, then the other strings lexicographically.
+func compareListElements(a, b string) int {
+	aInt, aErr := strconv.Atoi(a)
+	bInt, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil: return compareInts(aInt, bInt)
+	case aErr == nil:                return -1
+	case bErr == nil:                return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// Missing value, ordered before every other value.
+type NullData struct {}
+func (d NullData) Print() string {return "-"}
+func (d NullData) IntValue() int {return 0}
+func (d NullData) Compare(other Data) int {return 0}
+
+// Order of the cell types when comparing cells of different types.
+func dataTypeRank(d Data) int {
+	switch d.(type) {
+	case NullData:             return 0
+	case BoolData:             return 1
+	case IntData, FloatData:   return 2
+	case StrData, FoldStrData: return 3
+	case ListData:             return 4
+	}
+	return 5
+}
+
+// Orders any two cells: null values first, ints and floats by value, cells
+// of the same type with their Compare and otherwise by type, then by their
+// printed value.
+func CompareData(a, b Data) int {
+	_, aNull := a.(NullData)
+	_, bNull := b.(NullData)
+	switch {
+	case aNull || bNull:
+		return compareInts(dataTypeRank(a), dataTypeRank(b))
+	case reflect.TypeOf(a) == reflect.TypeOf(b):
+		return a.Compare(b)
+	}
+	aFloat, aNumber := numericData(a)
+	bFloat, bNumber := numericData(b)
+	if aNumber && bNumber {
+		return compareFloats(aFloat, bFloat)
+	}
+	if c := compareInts(dataTypeRank(a), dataTypeRank(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Print(), b.Print())
+}
+
+func numericData(d Data) (float64, bool) {
+	switch v := d.(type) {
+	case IntData:   return float64(v.V), true
+	case FloatData: return v.V, true
+	}
+	return 0, false
+}
+
+// Typed cell of a raw value: int, float64, bool, string, []string, nil
+// (null), a nil or non nil *int, *float64 or *string, or a Data.
+func NewData(raw interface{}) (Data, error) {
+	switch v := raw.(type) {
+	case nil:       return NullData{}, nil
+	case Data:      return v, nil
+	case int:       return IntData{V: v}, nil
+	case float64:   return FloatData{V: v}, nil
+	case bool:      return BoolData{V: v}, nil
+	case string:    return StrData{V: v}, nil
+	case []string:  return ListData{V: v}, nil
+	case *int:
+		if v == nil {
+			return NullData{}, nil
+		}
+		return IntData{V: *v}, nil
+	case *float64:
+		if v == nil {
+			return NullData{}, nil
+		}
+		return FloatData{V: *v}, nil
+	case *string:
+		if v == nil {
+			return NullData{}, nil
+		}
+		return StrData{V: *v}, nil
+	}
+	return nil, fmt.Errorf("unknown data type %T", raw)
+}
 
 
 type DataRow []Data
+
+type SortOrder int
+const (
+	SortOrder_Ascending  SortOrder = iota
+	SortOrder_Descending SortOrder = iota
+	SortOrder_MAX        SortOrder = iota
+)
+var SortOrderNames [SortOrder_MAX]string = [SortOrder_MAX]string {
+	"Ascending",
+	"Descending",
+}
+
 type RowSortEntry struct {
-	index   int
-	lessFun func(i,j int) bool 
+	index int
+	order SortOrder
+}
+// Sorts on the column at index in the given order (see CompareData), e.g.
+// CreateRowSortEntry(2, SortOrder_Descending).
+func CreateRowSortEntry(index int, order SortOrder) RowSortEntry {
+	return RowSortEntry{index: index, order: order}
 }
 
 type Header struct {
@@ -51,27 +237,34 @@ func CreateDataCollection(h ...string) *DataCollection{
 }
 
 // Sorting Capabilities
-func Smaller(i,j int) bool {return i<j}
-func Greater(i,j int) bool {return i>j}
-
 func (d *DataCollection) Len() int  { return len(d.rows) }
 func (d *DataCollection) Swap(i, j int) {
 	d.rows[i], d.rows[j] = d.rows[j], d.rows[i]
 }
 func (d *DataCollection) Less(i, j int) bool {
 	for _, entry := range d.sortEntries {
-		iVal, jVal := d.rows[i][entry.index].IntValue(), d.rows[j][entry.index].IntValue()
-		if iVal != jVal {
-			return entry.lessFun(iVal, jVal)
+		if c := CompareData(d.rows[i][entry.index], d.rows[j][entry.index]); c != 0 {
+			return c < 0 == (entry.order == SortOrder_Ascending)
 		}
 	}
 	return false
 }
+// Stable sort on the entries, the first one having precedence.
 func (d *DataCollection) Sort(sortEntries []RowSortEntry) {
 	if len(sortEntries) < 1 { panic("Need at least one data index to sort the data collection...") }
 
 	d.sortEntries = sortEntries
-	sort.Sort(d)
+	sort.Stable(d)
+}
+
+// Index of the column named name (case insensitive), -1 if there is none.
+func (d *DataCollection) ColumnIndex(name string) int {
+	for i, h := range d.header {
+		if strings.EqualFold(h.Name, name) {
+			return i
+		}
+	}
+	return -1
 }
 
 // Row Management
@@ -81,12 +274,11 @@ func (d *DataCollection) AddRow(rawrow ...interface{}) {
 	}
 	row := make([]Data, len(rawrow))
 	for i, rdata := range rawrow {
-		switch typeData := rdata.(type) {
-		case int:    row[i] = IntData{V: typeData}
-		case string: row[i] = StrData{V: typeData}
-		default:
+		data, err := NewData(rdata)
+		if err != nil {
 			panic(fmt.Sprintf("Unkown Data type when building row (data: %v, row: %v)", rdata, rawrow))
 		}
+		row[i] = data
 	}
 	d.rows = append(d.rows, row)
 }
@@ -112,15 +304,23 @@ func (d *DataCollection) Print() string {
 	for _, r := range d.rows {
 		for i, data := range r {
 			if i < len(r)-1 {
-				out += tabifyName(data.Print())
+				out += tabifyName(tableCell(data))
 			} else {
-				out += data.Print()+"\n"
+				out += tableCell(data)+"\n"
 			}
 		}
 	}
 	return out
 }
 
+// Printed cell of a table, floats being rounded (see formatFloat).
+func tableCell(data Data) string {
+	if f, ok := data.(FloatData); ok {
+		return formatFloat(f.V)
+	}
+	return data.Print()
+}
+
 func FilterCards(cards []*Card, predicate func(*Card) bool) []*Card {
 	filteredCards := make([]*Card, 0)
 
@@ -150,9 +350,12 @@ type DataCache struct {
 	TypeSynergyMap     *TypeMap
 	TraitSynergyMap    *TraitMap
 	PlayAreaSynergyMap *PlayAreaSynergyMap
+	TextIndex          *TextIndex
 }
 
-func (cache *DataCache) DumpStats() {
+// Prints the objective sets, then the number of cards and their average
+// cost by type, trait and keyword.
+func (cache *DataCache) DumpStats() error {
 	for i, set := range *cache.SetMap {
 		fmt.Println("Set #"+strconv.Itoa(i)+": "+set[0].Name)
 		// for _, c := range set {
@@ -160,21 +363,42 @@ func (cache *DataCache) DumpStats() {
 		// }
 	}
 
-	for i, cards := range *cache.TypeMap {
-		fmt.Println("Type: "+CardTypeNames[i]+":"+strconv.Itoa(len(cards)))
+	cards := make([]*Card, 0, len(*cache.CardMap))
+	for _, c := range *cache.CardMap {
+		cards = append(cards, c)
 	}
-
-	traitCollection := CreateDataCollection("Trait", "Card Number", "Synergy Cards")
-	for i, cards := range *cache.TraitMap {
-		traitCollection.AddRow(TraitNames[i], len(cards), len((*cache.TraitSynergyMap)[i]))
+	columns, err := CardColumnsByName("Type", "Traits", "Keywords", "Cost")
+	if err != nil {
+		return err
+	}
+	data := CardDataCollection(cards, columns)
+	for _, column := range []string{"Type", "Traits", "Keywords"} {
+		counts, err := cardCountsBy(data, column)
+		if err != nil {
+			return err
+		}
+		fmt.Print(counts.Print())
 	}
-	traitCollection.Sort([]RowSortEntry{{1, Greater}, {2, Smaller}, {0, Smaller}})
-	//traitCollection.FilterRow(func(r *DataRow) bool {return (*r)[2].IntValue() > 1})
-	fmt.Print(traitCollection.Print())
+	return nil
+}
 
-	for i, cards := range *cache.KeywordMap {
-		fmt.Println("Keyword: "+KeywordNames[i]+":"+strconv.Itoa(len(cards)))
+// Number of cards and their average cost per value of the column (per
+// element for list columns), most common first.
+func cardCountsBy(data *DataCollection, column string) (*DataCollection, error) {
+	exploded, err := data.Explode(column)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := exploded.GroupBy(column)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := groups.Aggregate(Aggregate(Agg_Count, ""), Aggregate(Agg_Avg, "Cost"))
+	if err != nil {
+		return nil, err
 	}
+	counts.Sort([]RowSortEntry{{1, SortOrder_Descending}, {0, SortOrder_Ascending}})
+	return counts, nil
 }
 
 func tabifyName(s string) string {
@@ -185,6 +409,17 @@ func tabifyName(s string) string {
 }
 
 func AnalyzeDB(db []Card) ([]Card, *DataCache) {
+	db, cache, err := AnalyzeDBChecked(db)
+	if err != nil {
+		panic(err.Error())
+	}
+	return db, cache
+}
+
+// Same as AnalyzeDB, but every violation is collected in a *ValidationReport
+// instead of panicking on the first one. Offending cards are left out of the
+// maps they would corrupt, the returned cache covers the rest of the DB.
+func AnalyzeDBChecked(db []Card) ([]Card, *DataCache, error) {
 	CardMap        	   := make(CardMap)
 	setMap         	   := make(SetMap)
 	typeMap        	   := make(TypeMap)
@@ -193,30 +428,43 @@ func AnalyzeDB(db []Card) ([]Card, *DataCache) {
 	typeSynergyMap 	   := make(TypeMap)
 	traitSynergyMap    := make(TraitMap)
 	playAreaSynergyMap := make(PlayAreaSynergyMap, 0)
+	report             := new(ValidationReport)
 
 	for i, c := range db {
 		// card definition uniqueness validation
 		if CardMap[c.Number] != nil {
-			panic("Card id "+strconv.Itoa(c.Number)+" is already present in DB, please merge them...")
-			
+			report.Add(c.Number, 0, Rule_DuplicateCardNumber,
+				"card id "+strconv.Itoa(c.Number)+" ("+c.Name+") is already present in DB as "+CardMap[c.Number].Name+", please merge them...")
+			continue
 		}
 		cardPointer := &db[i]
 		
 		CardMap[c.Number] = cardPointer
 
+		if c.Type == nil {
+			report.Add(c.Number, 0, Rule_MissingCardType, "card "+c.Name+" has no type")
+			continue
+		}
+
 		// set sanity validation
 		for _, objSet := range c.ObjectiveSets {
 			realIndex := objSet.CardSetNumber - 1
 			if realIndex < 0 || realIndex > 5 {
-				panic("Card "+strconv.Itoa(c.Number)+" has an invalid objective set card number: "+strconv.Itoa(objSet.CardSetNumber))
+				report.Add(c.Number, objSet.SetId, Rule_InvalidSetCardNumber,
+					"invalid objective set card number: "+strconv.Itoa(objSet.CardSetNumber))
+				continue
 			} else if realIndex == 0 && c.Type.GetType() != CardType_Objective {
-				panic("Trying to assing a non objective card as 1/6 for set #"+strconv.Itoa(objSet.SetId))
+				report.Add(c.Number, objSet.SetId, Rule_NonObjectiveFirstCard,
+					"trying to assign a non objective card as 1/6")
+				continue
 			}
 			
 			if setMap[objSet.SetId] == nil {
 				setMap[objSet.SetId] = new(ObjectiveSetDB)
-			} else if setMap[objSet.SetId][realIndex] != nil {
-				panic("Cannot add card "+strconv.Itoa(c.Number)+" to set #"+strconv.Itoa(objSet.SetId)+" as card "+strconv.Itoa(objSet.CardSetNumber)+" / 6")
+			} else if other := setMap[objSet.SetId][realIndex]; other != nil {
+				report.Add(c.Number, objSet.SetId, Rule_SetSlotAlreadyTaken,
+					"cannot add card as "+strconv.Itoa(objSet.CardSetNumber)+" / 6, already taken by card "+strconv.Itoa(other.Number))
+				continue
 			}
 			//fmt.Println("Adding "+c.Name+"in set "+strconv.Itoa(objSet.SetId))
 			setMap[objSet.SetId][realIndex] = cardPointer
@@ -252,10 +500,11 @@ func AnalyzeDB(db []Card) ([]Card, *DataCache) {
 		}
 	}
 
-	cache := &DataCache{&CardMap, &setMap, &typeMap, &keywordMap, &traitMap, &typeSynergyMap, &traitSynergyMap, &playAreaSynergyMap}
+	cache := &DataCache{&CardMap, &setMap, &typeMap, &keywordMap, &traitMap, &typeSynergyMap, &traitSynergyMap, &playAreaSynergyMap,
+		BuildTextIndex(&CardMap)}
 	//cache.DumpStats()
 	
-	return db, cache
+	return db, cache, report.Err()
 }
 
 func CreateDB() []Card {
@@ -288,7 +537,7 @@ func CreateDB() []Card {
 				Key(K_TargetedStrike),
 				Trait(Trait_Character),
 				Trait(Trait_ForceUser),
-				Reaction("After your opponent's turn begins, remove 1 focus token from this unit.", nil)},
+				Reaction("After your opponent's turn begins, remove 1 focus token from this unit.", nil).With(Trigger_AfterOpponentTurnBegins, Effect(Effect_RemoveFocus, Target_Self, 1))},
 			Health: 3,
 			Quote: "",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 1,CardSetNumber: 2}},
@@ -321,7 +570,7 @@ func CreateDB() []Card {
 			CardCombatIcons: nil,
 		        Abilities: AbilityList{
 				Trait(Trait_Weapon),
-				ConstantEffect("Enhanced Unit gains 1 Combat Damage and 1 Blast Damage.", nil)},
+				ConstantEffect("Enhanced Unit gains 1 Combat Damage and 1 Blast Damage.", nil).With(Trigger_Constant, CombatIconsEffect(Target_EnhancedCard, CombatModifier{CombatDamage: CombatIcon{1, 0}, BlastDamage: CombatIcon{1, 0}}))},
 			Health: 0,
 			Quote: "An elegant weapon for a more civilized age.",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 1, CardSetNumber: 4}},
@@ -337,7 +586,7 @@ func CreateDB() []Card {
 			CardCombatIcons: nil,
 		        Abilities: AbilityList{
 				Trait(Trait_Skill),
-				Action("Focus this enhancement to remove 1 focus token from enhanced unit.", nil)},
+				Action("Focus this enhancement to remove 1 focus token from enhanced unit.", nil).Paying(Effect(Effect_AddFocus, Target_Self, 1)).With(Trigger_Action, Effect(Effect_RemoveFocus, Target_EnhancedCard, 1))},
 			Health: 0,
 			Quote: "",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 1, CardSetNumber: 5}},
@@ -375,7 +624,7 @@ func CreateDB() []Card {
 			Abilities: AbilityList{
 				Trait(Trait_Dagobah),
 				ConstantEffect("Reduce the cost of the first enhancement you play each turn by 1.",
-					SynergyList{TypeSynergy(CardType_Enhancement, true)})},
+					SynergyList{TypeSynergy(CardType_Enhancement, true)}).With(Trigger_Constant, Effect(Effect_ReduceCost, Target_Controller, 1).Only(TypeSynergy(CardType_Enhancement, true)))},
 			Health: 5,
 			Quote: "\"What's in there?\"\n\"Only what you take with you.\"\n- The Empire Strikes Back",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 2, CardSetNumber: 1}},
@@ -393,7 +642,7 @@ func CreateDB() []Card {
 				Key(K_Elite),
 				Trait(Trait_Character),
 				Trait(Trait_ForceUser),
-				Reaction("After your opponent's turn begins, remove 1 focus token from this unit.", nil)},
+				Reaction("After your opponent's turn begins, remove 1 focus token from this unit.", nil).With(Trigger_AfterOpponentTurnBegins, Effect(Effect_RemoveFocus, Target_Self, 1))},
 			Health: 3,
 			Quote: "",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 2, CardSetNumber: 2}},
@@ -428,7 +677,7 @@ func CreateDB() []Card {
 			Abilities: AbilityList{
 				Trait(Trait_Skill),
 				Trait(Trait_LightSaberForm),
-				ConstantEffect("Damage from enhanced unit's CombatDamage icon type may be divided among any number of participating enemy units.", nil)},
+				ConstantEffect("Damage from enhanced unit's CombatDamage icon type may be divided among any number of participating enemy units.", nil).With(Trigger_Constant, Effect(Effect_GainTargetedStrike, Target_EnhancedCard, 0))},
 			Health: 0,
 			Quote: "\"...let go your conscious self and act on instinct.\"\n-Obi-Wan Kenobi, A New Hope",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 2, CardSetNumber: 4}},
@@ -448,7 +697,7 @@ func CreateDB() []Card {
 				Trait(Trait_Force),
 				Trait(Trait_Control),
 				Trait(Trait_Sense),
-				Interrupt("When an event card is played, cancel its effect.", SynergyList{TypeSynergy(CardType_Event, false)})},
+				Interrupt("When an event card is played, cancel its effect.", SynergyList{TypeSynergy(CardType_Event, false)}).With(Trigger_WhenEventPlayed, Effect(Effect_CancelEvent, Target_TriggeringCard, 0))},
 			Health: 0,
 			Quote: "For those strong in the force, action and reaction are the same.",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 2, CardSetNumber: 6}},
@@ -466,7 +715,7 @@ func CreateDB() []Card {
 			CardCombatIcons: nil,
 			Abilities: AbilityList{
 				Reaction("After you play a Force User unit, draw 1 card.",
-					SynergyList{TraitSynergy(Trait_ForceUser, true)})},
+					SynergyList{TraitSynergy(Trait_ForceUser, true)}).When(TraitSynergy(Trait_ForceUser, true)).With(Trigger_AfterUnitPlayed, Effect(Effect_DrawCards, Target_Controller, 1))},
 			Health: 5,
 			Quote: "\"The Force will be with you, always.\"\n-Obi-Wan Kenobi, A New Hope",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 3, CardSetNumber: 1}},
@@ -484,7 +733,7 @@ func CreateDB() []Card {
 				Key(K_Elite),
 				Trait(Trait_Character),
 				Trait(Trait_ForceUser),
-				ConstantEffect("While this unit is participating in an engagment, your opponent must place the first card of his edge stack faceup.", nil)},
+				ConstantEffect("While this unit is participating in an engagment, your opponent must place the first card of his edge stack faceup.", nil).With(Trigger_Constant, Effect(Effect_RevealEdgeCard, Target_Opponent, 1))},
 			Health: 3,
 			Quote: "",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 3, CardSetNumber: 2}},
@@ -522,7 +771,9 @@ func CreateDB() []Card {
 				Trait(Trait_Sense),
 				Trait(Trait_Alter),
 				Action("Place 1 focus token on a target Character or Creature unit. If the Balance of the Force is with the light side, place 2 focus tokens on that unit instead.",
-					SynergyList{TraitSynergy(Trait_Character, false), TraitSynergy(Trait_Creature, false)})},
+					SynergyList{TraitSynergy(Trait_Character, false), TraitSynergy(Trait_Creature, false)}).With(Trigger_Action,
+						Effect(Effect_AddFocus, Target_EnemyUnit, 1).Only(SynergyOptions(SynergyList{TraitSynergy(Trait_Character, true), TraitSynergy(Trait_Creature, true)})),
+						Effect(Effect_AddFocus, Target_EnemyUnit, 1).If(Condition_ForceWithLightSide))},
 			Health: 0,
 			Quote: "\"The Force can have a strong influence on the weak-minded.\"\n-Obi-Wan Kenobi, A New Hope",
 			ObjectiveSets: []ObjectiveSet{
@@ -540,7 +791,7 @@ func CreateDB() []Card {
 			CardCombatIcons: nil,
 			Abilities: AbilityList{
 				Action("Place 1 shield on a taret Character unit, even if that unit is already shielded.",
-					SynergyList{TraitSynergy(Trait_Character, true)})},
+					SynergyList{TraitSynergy(Trait_Character, true)}).With(Trigger_Action, Effect(Effect_AddShield, Target_FriendlyUnit, 1).Only(TraitSynergy(Trait_Character, true)))},
 			Health: 0,
 			Quote: "",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 3, CardSetNumber: 5}},
@@ -555,7 +806,7 @@ func CreateDB() []Card {
 			ForceIcons: 2,
 			CardCombatIcons: nil,
 			Abilities: AbilityList{
-				Action("Deal 1 damage to a target participating enemy unit.", SynergyList{TypeSynergy(CardType_Unit, false)})},
+				Action("Deal 1 damage to a target participating enemy unit.", SynergyList{TypeSynergy(CardType_Unit, false)}).With(Trigger_Action, Effect(Effect_DealDamage, Target_ParticipatingEnemyUnit, 1))},
 			Health: 0,
 			Quote: "",
 			ObjectiveSets: []ObjectiveSet{
@@ -575,7 +826,7 @@ func CreateDB() []Card {
 			CardCombatIcons: nil,
 			Abilities: AbilityList{
 				Trait(Trait_Dagobah),
-				Interrupt("When this objective is destroyed, search your objective deck to choose your next objective and put it into play immediately. Shuffle your objective deck.", nil)},
+				Interrupt("When this objective is destroyed, search your objective deck to choose your next objective and put it into play immediately. Shuffle your objective deck.", nil).With(Trigger_WhenObjectiveDestroyed, Effect(Effect_SearchObjective, Target_Controller, 1))},
 			Health: 4,
 			Quote: "",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 4, CardSetNumber: 1}},
@@ -624,7 +875,7 @@ func CreateDB() []Card {
 			CardCombatIcons: nil,
 			Abilities: AbilityList{
 					Reaction("After a Character unit is focused to strike, remove 1 focus token from that unit.",
-						SynergyList{TraitSynergy(Trait_Character, true)})},
+						SynergyList{TraitSynergy(Trait_Character, true)}).When(TraitSynergy(Trait_Character, true)).With(Trigger_AfterFocusedToStrike, Effect(Effect_RemoveFocus, Target_TriggeringCard, 1))},
 			Health: 0,
 			Quote: "\"Not as clumsy or as random as a blaster.\"\n-Obi-Wan Kenobi, A New Hope",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 4, CardSetNumber: 5}},
@@ -639,7 +890,7 @@ func CreateDB() []Card {
 			ForceIcons: 2,
 			CardCombatIcons: nil,
 			Abilities: AbilityList{
-					Action("If you are the attacking player, deal 1 damage to the engaged objective.", nil)},
+					Action("If you are the attacking player, deal 1 damage to the engaged objective.", nil).With(Trigger_Action, Effect(Effect_DealDamage, Target_EngagedObjective, 1).If(Condition_Attacking))},
 			Health: 0,
 			Quote: "",
 			ObjectiveSets: []ObjectiveSet{
@@ -660,7 +911,7 @@ func CreateDB() []Card {
 			Abilities: AbilityList{
 				Trait(Trait_Yavin4),
 				Interrupt("When 1 of your other objectives is engaged, your opponent engages this objective instead. [Limit once per turn.]",
-					nil)},
+					nil).With(Trigger_WhenObjectiveEngaged, Effect(Effect_RedirectEngagement, Target_Self, 0)).LimitOncePerTurn()},
 			Health: 6,
 			Quote: "The Rebels used the great temple on Yavin 4 to hide their command center, but never fully realized its mysterious history.",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 5, CardSetNumber: 1}},
@@ -677,7 +928,7 @@ func CreateDB() []Card {
 			Abilities: AbilityList{
 				Trait(Trait_Droid),
 				Interrupt("When an event card is played, sacrifice this unit to cancel the effects of that event card.",
-					SynergyList{TypeSynergy(CardType_Event, false)})},
+					SynergyList{TypeSynergy(CardType_Event, false)}).Paying(Effect(Effect_Sacrifice, Target_Self, 0)).With(Trigger_WhenEventPlayed, Effect(Effect_CancelEvent, Target_TriggeringCard, 0))},
 			Health: 3,
 			Quote: "\"Sir, if any of my circuits or gears will help, I'll gladly donate them.\"\n-C-3PO, A New Hope",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 5, CardSetNumber: 2}},
@@ -716,7 +967,8 @@ func CreateDB() []Card {
 			CardCombatIcons: nil,
 			Abilities: AbilityList{
 				Interrupt("When damage is dealt to a friendly non-Vehicle unit, deal 1 point of that damage to another target unit instead.",
-					SynergyList{AccumulateSynergies(SynergyList{TypeSynergy(CardType_Unit, true), InvertSynergy(TraitSynergy(Trait_Vehicule, true))})})},
+					SynergyList{AccumulateSynergies(SynergyList{TypeSynergy(CardType_Unit, true), InvertSynergy(TraitSynergy(Trait_Vehicule, true))})}).When(AccumulateSynergies(SynergyList{TypeSynergy(CardType_Unit, true), InvertSynergy(TraitSynergy(Trait_Vehicule, true))})).
+					With(Trigger_WhenDamageDealt, Effect(Effect_RedirectDamage, Target_AnyUnit, 1))},
 			Health: 0,
 			Quote: "\"Good against remotes is on thing. Good against the living? That's something else.\"\n-han Solo, A New Hope",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 5, CardSetNumber: 5}},
@@ -731,7 +983,7 @@ func CreateDB() []Card {
 			ForceIcons: 0,
 			CardCombatIcons: nil,
 			Abilities: AbilityList{
-				Action("Cancel this edge battle and the card effects of all other fate cards just revealed. Discard both edge stacks and start a new edge battle..", nil)},
+				Action("Cancel this edge battle and the card effects of all other fate cards just revealed. Discard both edge stacks and start a new edge battle..", nil).With(Trigger_Action, Effect(Effect_CancelEdgeBattle, Target_Self, 0))},
 			Health: 0,
 			Quote: "",
 			ObjectiveSets: []ObjectiveSet{
@@ -752,7 +1004,7 @@ func CreateDB() []Card {
 		Abilities: AbilityList{
 				Trait(Trait_CloudCity),
 				Reaction("After you refresh, remove 1 damage from a target unit.",
-					SynergyList{TypeSynergy(CardType_Unit, true)})},
+					SynergyList{TypeSynergy(CardType_Unit, true)}).With(Trigger_AfterRefresh, Effect(Effect_RemoveDamage, Target_FriendlyUnit, 1))},
 			Health: 5,
 			Quote: "The Rebel alliance is outnumbered, outgunned, and commpletely overmatched. Yet still they have hope.",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 6, CardSetNumber: 1}},
@@ -770,7 +1022,7 @@ func CreateDB() []Card {
 				Trait(Trait_Vehicule),
 				Trait(Trait_CapitalShip),
 				Interrupt("When a Character unit is destroyed, return it to its owner's hand instead of placing it in its owner's discard pile. [Limit once per turn.]",
-					SynergyList{TraitSynergy(Trait_Character, true)})},
+					SynergyList{TraitSynergy(Trait_Character, true)}).When(TraitSynergy(Trait_Character, true)).With(Trigger_WhenUnitDestroyed, Effect(Effect_ReturnToHand, Target_TriggeringCard, 0)).LimitOncePerTurn()},
 			Health: 4,
 			Quote: "",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 6,CardSetNumber: 2}},
@@ -803,7 +1055,7 @@ func CreateDB() []Card {
 			CardCombatIcons: nil,
 		Abilities: AbilityList{
 				Action("Put a Force User unit into play from your discard pile.",
-					SynergyList{TraitSynergy(Trait_ForceUser, true)})},
+					SynergyList{TraitSynergy(Trait_ForceUser, true)}).With(Trigger_Action, Effect(Effect_PutIntoPlay, Target_FriendlyDiscard, 1).Only(AccumulateSynergies(SynergyList{TypeSynergy(CardType_Unit, true), TraitSynergy(Trait_ForceUser, true)})))},
 			Health: 0,
 			Quote: "\"Luke, the Force runs strong in your family. Pass on what you have learned.\"\n-Yoda, Return of the Jedi",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 6, CardSetNumber: 4}},
@@ -817,7 +1069,7 @@ func CreateDB() []Card {
 			Ressources: 0,
 			ForceIcons: 1,
 			CardCombatIcons: nil,
-		        Abilities: AbilityList{Action("Remove all damage from a targe3t objective. [Play only during your turn]", nil)},
+		        Abilities: AbilityList{Action("Remove all damage from a targe3t objective. [Play only during your turn]", nil).With(Trigger_Action, Effect(Effect_RemoveAllDamage, Target_FriendlyObjective, 0).If(Condition_YourTurn))},
 			Health: 0,
 			Quote: "",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 6, CardSetNumber: 5}},
@@ -835,7 +1087,7 @@ func CreateDB() []Card {
 				Trait(Trait_Force),
 				Trait(Trait_Control),
 				Action("Discard any number of tokens and enhancements from a target friendly Character unit.",
-					SynergyList{TraitSynergy(Trait_Character, true)})},
+					SynergyList{TraitSynergy(Trait_Character, true)}).With(Trigger_Action, Effect(Effect_DiscardTokens, Target_FriendlyUnit, 0).Only(TraitSynergy(Trait_Character, true)))},
 			Health: 0,
 			Quote: "",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 6, CardSetNumber: 6}},
@@ -851,7 +1103,7 @@ func CreateDB() []Card {
 			Ressources: 1,
 			ForceIcons: 0,
 			CardCombatIcons: nil,
-		Abilities: AbilityList{ConstantEffect("This objective contributes 1 Force icon to your side during the Force struggle.", nil)},
+		Abilities: AbilityList{ConstantEffect("This objective contributes 1 Force icon to your side during the Force struggle.", nil).With(Trigger_Constant, Effect(Effect_ForceIcons, Target_Controller, 1))},
 			Health: 5,
 			Quote: "Jedi were once trained at the Jedi Temple on Coruscant. Now, the few that remain make due with what's available.",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 7, CardSetNumber: 1}},
@@ -870,7 +1122,7 @@ func CreateDB() []Card {
 			CardCombatIcons: nil,
 		        Abilities: AbilityList{
 				Trait(Trait_Location),
-				ConstantEffect("This enhancement contributes 1 Force icon to your side during the Force struggle.", nil)},
+				ConstantEffect("This enhancement contributes 1 Force icon to your side during the Force struggle.", nil).With(Trigger_Constant, Effect(Effect_ForceIcons, Target_Controller, 1))},
 			Health: 0,
 			Quote: "",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 7,CardSetNumber: 4}},
@@ -886,7 +1138,9 @@ func CreateDB() []Card {
 			Ressources: 0,
 			ForceIcons: 1,
 			CardCombatIcons: nil,
-			Abilities: AbilityList{Action("Return the top card of your discard pile to your hand. If the Balance of the Force is with the light side, return the top 2 cards instead.", nil)},
+			Abilities: AbilityList{Action("Return the top card of your discard pile to your hand. If the Balance of the Force is with the light side, return the top 2 cards instead.", nil).With(Trigger_Action,
+				Effect(Effect_ReturnToHand, Target_Controller, 1),
+				Effect(Effect_ReturnToHand, Target_Controller, 1).If(Condition_ForceWithLightSide))},
 			Health: 0,
 			Quote: "",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 7, CardSetNumber: 6}},
@@ -903,7 +1157,7 @@ func CreateDB() []Card {
 			ForceIcons: 0,
 			CardCombatIcons: nil,
 			Abilities: AbilityList{
-				Reaction("After you win an edge battle as the attacker, deal 1 damage to the engaged objective. [Limit once per turn].", nil)},
+				Reaction("After you win an edge battle as the attacker, deal 1 damage to the engaged objective. [Limit once per turn].", nil).With(Trigger_AfterEdgeBattleWon, Effect(Effect_DealDamage, Target_EngagedObjective, 1).If(Condition_Attacking)).LimitOncePerTurn()},
 			Health: 4,
 			Quote: "",
 			ObjectiveSets: []ObjectiveSet{ObjectiveSet{SetId: 18, CardSetNumber: 1}},
@@ -920,7 +1174,7 @@ func CreateDB() []Card {
 		        Abilities: AbilityList{
 				Trait(Trait_Character),
 				ConstantEffect("While this unit is participating in an engagement, you may resolve the effects of each fate card in your edge stack an additional time.",
-					SynergyList{TypeSynergy(CardType_Fate, true)})},
+					SynergyList{TypeSynergy(CardType_Fate, true)}).With(Trigger_Constant, Effect(Effect_ResolveFatesTwice, Target_Controller, 1))},
 			Health: 1,
 			Quote: "",
 			ObjectiveSets: []ObjectiveSet{
@@ -0,0 +1,81 @@
+package swcg
+
+import "reflect"
+import "testing"
+
+// Printed cells of the column, in row order.
+func columnValues(d *DataCollection, index int) []string {
+	values := make([]string, len(d.rows))
+	for i, row := range d.rows {
+		values[i] = row[index].Print()
+	}
+	return values
+}
+
+func TestDataCollectionSort(t *testing.T) {
+	empty, null := "", (*string)(nil)
+	tests := []struct {
+		name     string
+		values   []interface{}
+		order    SortOrder
+		expected []string
+	}{
+		{"empty strings first", []interface{}{"b", "", "a"}, SortOrder_Ascending, []string{"", "a", "b"}},
+		{"nulls first", []interface{}{"b", null, &empty, 2}, SortOrder_Ascending, []string{"-", "2", "", "b"}},
+		{"nulls last when descending", []interface{}{null, "a", ""}, SortOrder_Descending, []string{"a", "", "-"}},
+		{"names ignore the case", []interface{}{FoldStrData{V: "luke"}, FoldStrData{V: "Leia"}, FoldStrData{V: "Luke"}},
+			SortOrder_Ascending, []string{"Leia", "Luke", "luke"}},
+		{"numbers by value", []interface{}{10, 2.5, 9}, SortOrder_Ascending, []string{"2.5", "9", "10"}},
+		{"set ids by value", []interface{}{[]string{"18"}, []string{"2", "18"}, []string{}, []string{"2"}},
+			SortOrder_Ascending, []string{"-", "2", "2,18", "18"}},
+	}
+	for _, test := range tests {
+		d := CreateDataCollection("Value")
+		for _, v := range test.values {
+			d.AddRow(v)
+		}
+		d.Sort([]RowSortEntry{CreateRowSortEntry(0, test.order)})
+		if values := columnValues(d, 0); !reflect.DeepEqual(values, test.expected) {
+			t.Errorf("%s: got %q, expected %q", test.name, values, test.expected)
+		}
+	}
+}
+
+func TestDataCollectionSortOnSeveralColumns(t *testing.T) {
+	d := CreateDataCollection("Name", "Cost", "Force")
+	d.AddRow("A", 1, 2)
+	d.AddRow("B", 2, 1)
+	d.AddRow("C", 1, 2)
+	d.AddRow("D", 1, 3)
+	d.Sort([]RowSortEntry{CreateRowSortEntry(1, SortOrder_Ascending), CreateRowSortEntry(2, SortOrder_Descending)})
+	if names := columnValues(d, 0); !reflect.DeepEqual(names, []string{"D", "A", "C", "B"}) {
+		t.Errorf("got %q, expected the first entry to have precedence and ties to keep their order", names)
+	}
+}
+
+func TestCardCountsBy(t *testing.T) {
+	cards := []*Card{
+		{Name: "A", Type: Type(CardType_Unit), Cost: 2, Abilities: AbilityList{Trait(Trait_Character), Trait(Trait_ForceUser)}},
+		{Name: "B", Type: Type(CardType_Unit), Cost: 4, Abilities: AbilityList{Trait(Trait_Character)}},
+		{Name: "C", Type: Type(CardType_Event), Cost: 1},
+	}
+	columns, err := CardColumnsByName("Traits", "Cost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts, err := cardCountsBy(CardDataCollection(cards, columns), "Traits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []DataRow{
+		{StrData{V: "Character"}, IntData{V: 2}, FloatData{V: 3}},
+		{NullData{}, IntData{V: 1}, FloatData{V: 1}},
+		{StrData{V: "ForceUser"}, IntData{V: 1}, FloatData{V: 2}},
+	}
+	if !reflect.DeepEqual(counts.rows, expected) {
+		t.Errorf("got %v, expected %v", counts.rows, expected)
+	}
+	if _, err := cardCountsBy(CardDataCollection(cards, columns), "Keywords"); err == nil {
+		t.Errorf("counting by a missing column should fail")
+	}
+}
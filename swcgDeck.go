@@ -0,0 +1,158 @@
+package swcg
+
+import "fmt"
+import "strconv"
+
+// Decks ----------------------------------------------------------------------
+
+const DeckObjectiveSetCount = 10
+const DeckMaxSetCopies      = 2
+
+// A deck is a list of objective sets, each one contributing its objective
+// to the objective deck and its 5 other cards to the command deck, built
+// around the affiliation of its faction.
+type Deck struct {
+	Faction     CardFaction
+	Affiliation *Affiliation
+	SetIds      []int
+	Sets        []*ObjectiveSetDB
+}
+
+// Looks up every set id in cache.SetMap and attaches the faction's
+// affiliation. Only unknown set ids are reported here, the construction
+// rules are checked by Validate.
+func CreateDeck(cache *DataCache, faction CardFaction, setIds ...int) (*Deck, error) {
+	d := &Deck{Faction: faction, Affiliation: AffiliationFor(faction),
+		SetIds: make([]int, 0, len(setIds)), Sets: make([]*ObjectiveSetDB, 0, len(setIds))}
+	for _, id := range setIds {
+		if err := d.AddSet(cache, id); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+func (d *Deck) AddSet(cache *DataCache, setId int) error {
+	set := (*cache.SetMap)[setId]
+	if set == nil {
+		return fmt.Errorf("unknown objective set #%d", setId)
+	}
+	d.SetIds = append(d.SetIds, setId)
+	d.Sets = append(d.Sets, set)
+	return nil
+}
+
+func (d *Deck) RemoveSet(setId int) bool {
+	for i, id := range d.SetIds {
+		if id == setId {
+			d.SetIds = append(d.SetIds[:i], d.SetIds[i+1:]...)
+			d.Sets = append(d.Sets[:i], d.Sets[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Deck) Side() CardSide {
+	return d.Faction.Side()
+}
+
+// Number of copies of each objective set in the deck.
+func (d *Deck) SetCopies() map[int]int {
+	copies := make(map[int]int)
+	for _, id := range d.SetIds {
+		copies[id]++
+	}
+	return copies
+}
+
+// Cards 2 to 6 of every set, in set order.
+func (d *Deck) CommandDeck() []*Card {
+	cards := make([]*Card, 0, len(d.Sets)*5)
+	for _, set := range d.Sets {
+		for _, c := range set[1:] {
+			if c != nil {
+				cards = append(cards, c)
+			}
+		}
+	}
+	return cards
+}
+
+// The objective card of every set, in set order.
+func (d *Deck) ObjectiveDeck() []*Card {
+	cards := make([]*Card, 0, len(d.Sets))
+	for _, set := range d.Sets {
+		if set[0] != nil {
+			cards = append(cards, set[0])
+		}
+	}
+	return cards
+}
+
+// Deck Validation ------------------------------------------------------------
+
+// Checks the deck construction rules: an affiliation of the deck's faction,
+// exactly 10 objective sets, at most 2 copies of a set, complete sets, a
+// single side of the Force, and objective sets only available to a faction
+// restricted to decks of that faction.
+// Returns a *ValidationReport listing every violation, or nil.
+//
+// A faction-only objective set of a neutral faction can't be restricted to
+// any deck faction, so it is allowed in every deck of its side.
+func (d *Deck) Validate() error {
+	report := new(ValidationReport)
+
+	if len(d.SetIds) != DeckObjectiveSetCount {
+		report.Add(0, 0, Rule_DeckSetCount,
+			"deck has "+strconv.Itoa(len(d.SetIds))+" objective sets instead of "+strconv.Itoa(DeckObjectiveSetCount))
+	}
+
+	if d.Faction < 0 || d.Faction >= Faction_MAX || d.Faction.IsNeutral() {
+		report.Add(0, 0, Rule_DeckNeutralFaction, "a deck must be built for a non neutral faction")
+		return report.Err()
+	}
+	if d.Affiliation == nil {
+		report.Add(0, 0, Rule_DeckAffiliation, "deck has no affiliation")
+	} else if d.Affiliation.Faction != d.Faction {
+		report.Add(0, 0, Rule_DeckAffiliation,
+			"the "+d.Affiliation.Name+" affiliation doesn't belong to the deck's faction "+FactionNames[d.Faction])
+	}
+
+	reported := make(map[int]bool)
+	copies := d.SetCopies()
+	for i, set := range d.Sets {
+		setId := d.SetIds[i]
+		if reported[setId] {
+			continue
+		}
+		reported[setId] = true
+
+		if copies[setId] > DeckMaxSetCopies {
+			report.Add(0, setId, Rule_DeckSetCopies,
+				"deck has "+strconv.Itoa(copies[setId])+" copies of the set, at most "+strconv.Itoa(DeckMaxSetCopies)+" allowed")
+		}
+
+		for j, c := range set {
+			if c == nil {
+				report.Add(0, setId, Rule_DeckIncompleteSet, "objective set is missing card "+strconv.Itoa(j+1)+" / 6")
+				continue
+			}
+			if c.Faction.Side() != d.Side() {
+				report.Add(c.Number, setId, Rule_DeckMixedSides,
+					c.Name+" is a "+SideNames[c.Faction.Side()]+" side card in a "+SideNames[d.Side()]+" side deck")
+			}
+		}
+
+		objective := set[0]
+		if objective == nil {
+			continue
+		}
+		if objType, ok := objective.Type.(*ObjectiveCardType); ok && objType.OnlyAvailableToFaction &&
+			d.SetAffinity(set) == Affinity_OutOfFaction {
+			report.Add(objective.Number, setId, Rule_DeckFactionRestricted,
+				objective.Name+" is only available to "+FactionNames[objective.Faction]+" decks, not "+FactionNames[d.Faction])
+		}
+	}
+	return report.Err()
+}
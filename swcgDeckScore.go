@@ -0,0 +1,130 @@
+package swcg
+
+import "sort"
+
+// Deck Synergy Score ---------------------------------------------------------
+//
+// Counts the synergy edges between the cards of a deck, every copy of a
+// card counting on its own: two copies of a set doubling the edges going out
+// of and into its cards. Positive synergies are counted within the deck,
+// negative ones against the cards of a reference opponent deck. Synergies of
+// a card with its own copies aren't counted, the graph having no loops.
+
+type SetContribution struct {
+	SetId     int
+	Copies    int
+	Provided  int         // positive edges from its cards to the cards of other sets
+	Received  int         // positive edges from the cards of other sets to its cards
+	Internal  int         // positive edges between its own cards
+	Opponent  int         // negative edges to the opponent's cards
+	DependsOn map[int]int // set id -> positive edges received from it
+}
+
+// Synergy edges the set takes part in, each one counting for both ends.
+func (c *SetContribution) Value() int {
+	return c.Provided + c.Received + c.Internal + c.Opponent
+}
+
+type DeckScore struct {
+	Positive int // positive edges within the deck
+	Negative int // negative edges against the opponent's deck
+	Sets     []SetContribution // by set id
+}
+
+func (s *DeckScore) Total() int { return s.Positive + s.Negative }
+
+// The set contributing the least to the deck's score, the first candidate to
+// replace (lowest set id on ties).
+func (s *DeckScore) WeakestSet() *SetContribution {
+	var weakest *SetContribution
+	for i := range s.Sets {
+		if c := &s.Sets[i]; weakest == nil || c.Value()*weakest.Copies < weakest.Value()*c.Copies {
+			weakest = c
+		}
+	}
+	return weakest
+}
+
+type deckCardInstance struct {
+	setId int
+	card  *Card
+}
+
+func deckCardInstances(d *Deck) []deckCardInstance {
+	instances := make([]deckCardInstance, 0, len(d.Sets)*6)
+	for i, set := range d.Sets {
+		for _, c := range set {
+			if c != nil {
+				instances = append(instances, deckCardInstance{d.SetIds[i], c})
+			}
+		}
+	}
+	return instances
+}
+
+// Scores the deck against the opponent's deck, which may be nil to only
+// count the positive synergies.
+func ScoreDeck(graph *SynergyGraph, deck, opponent *Deck) *DeckScore {
+	type edgeKey struct {
+		from, to int
+		positive bool
+	}
+	edges := make(map[edgeKey]bool)
+	for _, list := range graph.Out {
+		for _, e := range list {
+			edges[edgeKey{e.From, e.To, e.Positive}] = true
+		}
+	}
+
+	score := &DeckScore{}
+	contributions := make(map[int]*SetContribution)
+	for id, copies := range deck.SetCopies() {
+		contributions[id] = &SetContribution{SetId: id, Copies: copies, DependsOn: make(map[int]int)}
+	}
+
+	cards := deckCardInstances(deck)
+	for _, from := range cards {
+		for _, to := range cards {
+			if !edges[edgeKey{from.card.Number, to.card.Number, true}] {
+				continue
+			}
+			score.Positive++
+			if from.setId == to.setId {
+				contributions[from.setId].Internal++
+			} else {
+				contributions[from.setId].Provided++
+				contributions[to.setId].Received++
+				contributions[to.setId].DependsOn[from.setId]++
+			}
+		}
+	}
+	if opponent != nil {
+		for _, from := range cards {
+			for _, to := range deckCardInstances(opponent) {
+				if edges[edgeKey{from.card.Number, to.card.Number, false}] {
+					score.Negative++
+					contributions[from.setId].Opponent++
+				}
+			}
+		}
+	}
+
+	for _, c := range contributions {
+		score.Sets = append(score.Sets, *c)
+	}
+	sort.Slice(score.Sets, func(i, j int) bool { return score.Sets[i].SetId < score.Sets[j].SetId })
+	return score
+}
+
+// One row per objective set of the deck.
+func (s *DeckScore) DataCollection(cache *DataCache) *DataCollection {
+	data := CreateDataCollection("Set", "Objective", "Copies", "Provided", "Received", "Internal", "Opponent", "Value")
+	for _, c := range s.Sets {
+		name := "-"
+		if set, ok := (*cache.SetMap)[c.SetId]; ok && set[0] != nil {
+			name = set[0].Name
+		}
+		data.AddRow(c.SetId, name, c.Copies, c.Provided, c.Received, c.Internal, c.Opponent, c.Value())
+	}
+	return data
+}
@@ -0,0 +1,200 @@
+package swcg
+
+import "math/rand/v2"
+import "sort"
+
+// Edge Battles ---------------------------------------------------------------
+
+const (
+	Edge_Attacker = 0
+	Edge_Defender = 1
+	Edge_None     = -1
+)
+
+// Cards committed face down by one player, and that player's units
+// participating in the engagement (for their Edge (n) keyword).
+type EdgeStack struct {
+	Cards []*Card
+	Units []*Card
+}
+
+func (s EdgeStack) ForceIcons() int {
+	icons := 0
+	for _, c := range s.Cards {
+		icons += c.ForceIcons
+	}
+	return icons
+}
+
+func (s EdgeStack) EdgeBonus() int {
+	bonus := 0
+	for _, u := range s.Units {
+		bonus += EdgeKeywordValue(u)
+	}
+	return bonus
+}
+
+// Sum of the Edge (n) keywords of the card.
+func EdgeKeywordValue(c *Card) int {
+	value := 0
+	for _, ability := range c.Abilities {
+		if k, ok := ability.(*ComplexKeyword); ok && k.K == K_Edge {
+			value += k.V
+		}
+	}
+	return value
+}
+
+type FateResolution struct {
+	Card     *Card
+	Side     int
+	Canceled bool
+}
+
+type EdgeBattleResult struct {
+	Icons    [2]int // Force icons of each side, Edge (n) bonuses included
+	Winner   int    // Edge_Attacker, Edge_Defender or Edge_None when canceled (see ResolveEdgeBattles)
+	Fates    []FateResolution
+	Canceled bool   // a fate card canceled the battle, both stacks are discarded and a new one starts
+}
+
+// Reveals both stacks and resolves the fate cards by increasing
+// EdgeBattlePriority (the attacker's first on equal priority). The first
+// fate card canceling the edge battle cancels every other fate card and
+// leaves the battle without a winner. Otherwise the side with the most Force icons
+// wins, the defender winning ties.
+func ResolveEdgeBattle(attacker, defender EdgeStack) *EdgeBattleResult {
+	stacks := [2]EdgeStack{attacker, defender}
+	result := &EdgeBattleResult{Winner: Edge_None, Fates: make([]FateResolution, 0)}
+
+	for side, stack := range stacks {
+		result.Icons[side] = stack.ForceIcons() + stack.EdgeBonus()
+		for _, c := range stack.Cards {
+			if _, ok := c.Type.(*FateCardType); ok {
+				result.Fates = append(result.Fates, FateResolution{Card: c, Side: side})
+			}
+		}
+	}
+	sort.SliceStable(result.Fates, func(i, j int) bool {
+		return result.Fates[i].Card.Type.(*FateCardType).EdgeBattlePriority < result.Fates[j].Card.Type.(*FateCardType).EdgeBattlePriority
+	})
+
+	for i := range result.Fates {
+		if CardCancelsEdgeBattle(result.Fates[i].Card) {
+			for j := range result.Fates {
+				result.Fates[j].Canceled = j != i
+			}
+			result.Canceled = true
+			return result
+		}
+	}
+
+	result.Winner = Edge_Defender
+	if result.Icons[Edge_Attacker] > result.Icons[Edge_Defender] {
+		result.Winner = Edge_Attacker
+	}
+	return result
+}
+
+// Resolves edge battles until one isn't canceled, asking nextStacks for the
+// stacks of each new battle. After maxBattles canceled battles the defender
+// wins the edge, as in a game. Returns every result, the last one deciding
+// the winner.
+func ResolveEdgeBattles(attacker, defender EdgeStack, nextStacks func(battle int) (EdgeStack, EdgeStack), maxBattles int) []*EdgeBattleResult {
+	results := make([]*EdgeBattleResult, 0, 1)
+	for battle := 0; battle < maxBattles; battle++ {
+		if battle > 0 {
+			attacker, defender = nextStacks(battle)
+		}
+		result := ResolveEdgeBattle(attacker, defender)
+		results = append(results, result)
+		if !result.Canceled {
+			break
+		}
+	}
+	if last := results[len(results)-1]; last.Canceled {
+		last.Winner = Edge_Defender
+	}
+	return results
+}
+
+// Edge Odds ------------------------------------------------------------------
+
+type EdgeOdds struct {
+	Win      float64
+	Loss     float64
+	Canceled float64 // every battle was canceled until the battle limit, a win for the defender counted in Win or Loss
+	Trials   int
+}
+
+type EdgeOddsConfig struct {
+	Stack         []*Card // our edge stack
+	Units         []*Card // our participating units
+	AsAttacker    bool
+	Opponent      *Deck   // the opponent's stack is drawn from its command deck, nil for no cards
+	OpponentCards int     // number of cards in the opponent's stack
+	OpponentUnits []*Card
+	Trials        int
+	Seed          uint64
+}
+
+// Edge battles fought in an engagement before the defender wins the edge.
+const MaxEdgeBattles = 4
+
+// Estimates the odds of winning the edge battle with the given stack against
+// random opponent stacks. When a battle is canceled, we start the new one
+// with an empty stack (the hand was committed) while the opponent commits
+// a new random stack from the rest of its deck.
+func EdgeWinProbability(config EdgeOddsConfig) EdgeOdds {
+	odds := EdgeOdds{Trials: config.Trials}
+	if config.Trials <= 0 {
+		return odds
+	}
+	rng := rand.New(rand.NewPCG(config.Seed, 0))
+	var deck []*Card
+	if config.Opponent != nil {
+		deck = config.Opponent.CommandDeck()
+	}
+	ourSide := Edge_Defender
+	if config.AsAttacker {
+		ourSide = Edge_Attacker
+	}
+
+	wins, canceled := 0, 0
+	for trial := 0; trial < config.Trials; trial++ {
+		rng.Shuffle(len(deck), func(i, j int) { deck[i], deck[j] = deck[j], deck[i] })
+		drawn := 0
+		draw := func() []*Card {
+			n := max(0, config.OpponentCards)
+			if drawn+n > len(deck) {
+				n = len(deck) - drawn
+			}
+			cards := deck[drawn : drawn+n]
+			drawn += n
+			return cards
+		}
+
+		stacks := func(ours []*Card) (EdgeStack, EdgeStack) {
+			us := EdgeStack{Cards: ours, Units: config.Units}
+			them := EdgeStack{Cards: draw(), Units: config.OpponentUnits}
+			if ourSide == Edge_Attacker {
+				return us, them
+			}
+			return them, us
+		}
+		attacker, defender := stacks(config.Stack)
+		results := ResolveEdgeBattles(attacker, defender, func(int) (EdgeStack, EdgeStack) { return stacks(nil) }, MaxEdgeBattles)
+
+		last := results[len(results)-1]
+		if last.Winner == ourSide {
+			wins++
+		}
+		if last.Canceled {
+			canceled++
+		}
+	}
+	odds.Win = float64(wins) / float64(config.Trials)
+	odds.Loss = 1 - odds.Win
+	odds.Canceled = float64(canceled) / float64(config.Trials)
+	return odds
+}
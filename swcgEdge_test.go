@@ -0,0 +1,68 @@
+package swcg
+
+import "testing"
+
+func testCard(db []Card, name string) *Card {
+	for i := range db {
+		if db[i].Name == name {
+			return &db[i]
+		}
+	}
+	panic("no card named " + name)
+}
+
+func TestEdgeWinProbabilityWithoutOpponent(t *testing.T) {
+	db := CreateDB()
+	stack := []*Card{testCard(db, "Obi-Wan Kenobi")}
+	tests := []struct {
+		config   EdgeOddsConfig
+		expected EdgeOdds
+	}{
+		{EdgeOddsConfig{Stack: stack, AsAttacker: true, OpponentCards: 2, Trials: 10}, EdgeOdds{Win: 1, Trials: 10}},
+		{EdgeOddsConfig{AsAttacker: true, OpponentCards: 2, Trials: 10}, EdgeOdds{Loss: 1, Trials: 10}},
+		{EdgeOddsConfig{AsAttacker: false, OpponentCards: -1, Trials: 10}, EdgeOdds{Win: 1, Trials: 10}},
+		{EdgeOddsConfig{Stack: stack, Trials: 0}, EdgeOdds{}},
+	}
+	for i, test := range tests {
+		if odds := EdgeWinProbability(test.config); odds != test.expected {
+			t.Errorf("test %d: got %+v, expected %+v", i, odds, test.expected)
+		}
+	}
+}
+
+func TestEdgeWinProbabilityIsSeeded(t *testing.T) {
+	_, cache := AnalyzeDB(CreateDB())
+	opponent, err := CreateDeck(cache, Faction_Jedi, 1, 1, 2, 2, 3, 3, 4, 5, 6, 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := EdgeOddsConfig{Stack: opponent.CommandDeck()[:2], Opponent: opponent, OpponentCards: 2, Trials: 500, Seed: 7}
+	odds := EdgeWinProbability(config)
+	if odds != EdgeWinProbability(config) {
+		t.Errorf("same seed, different odds")
+	}
+	if total := odds.Win + odds.Loss; total < 0.999 || total > 1.001 || odds.Win == 0 || odds.Loss == 0 {
+		t.Errorf("unexpected odds %+v", odds)
+	}
+}
+
+func TestEdgeBattleLimitGoesToTheDefender(t *testing.T) {
+	twist := testCard(CreateDB(), "Twist of Fate")
+	opponent := &Deck{Sets: []*ObjectiveSetDB{{nil, twist, twist, twist, twist, twist}}}
+	for _, asAttacker := range []bool{true, false} {
+		config := EdgeOddsConfig{Stack: []*Card{twist}, AsAttacker: asAttacker, Opponent: opponent, OpponentCards: 1, Trials: 10}
+		expected := EdgeOdds{Win: 1, Canceled: 1, Trials: 10}
+		if asAttacker {
+			expected = EdgeOdds{Loss: 1, Canceled: 1, Trials: 10}
+		}
+		if odds := EdgeWinProbability(config); odds != expected {
+			t.Errorf("as attacker %v: got %+v, expected %+v", asAttacker, odds, expected)
+		}
+	}
+
+	stack := EdgeStack{Cards: []*Card{twist}}
+	results := ResolveEdgeBattles(stack, stack, func(int) (EdgeStack, EdgeStack) { return stack, stack }, MaxEdgeBattles)
+	if len(results) != MaxEdgeBattles || results[len(results)-1].Winner != Edge_Defender {
+		t.Errorf("got %d battles, the last won by %d", len(results), results[len(results)-1].Winner)
+	}
+}
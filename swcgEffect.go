@@ -0,0 +1,250 @@
+package swcg
+
+// Card Effects ---------------------------------------------------------------
+//
+// Typed version of the ability descriptions: an ability is triggered by an
+// EffectTrigger, optionally restricted to the triggering cards matching its
+// TriggerFilter, pays its Costs then resolves its Effects in order.
+
+type EffectTrigger int
+const (
+	Trigger_None                    EffectTrigger = iota // not scripted
+	Trigger_Action                  EffectTrigger = iota // played event, revealed fate or used card ability
+	Trigger_Constant                EffectTrigger = iota
+	Trigger_AfterOpponentTurnBegins EffectTrigger = iota
+	Trigger_AfterRefresh            EffectTrigger = iota
+	Trigger_AfterUnitPlayed         EffectTrigger = iota
+	Trigger_AfterFocusedToStrike    EffectTrigger = iota
+	Trigger_AfterEdgeBattleWon      EffectTrigger = iota
+	Trigger_WhenEventPlayed         EffectTrigger = iota
+	Trigger_WhenDamageDealt         EffectTrigger = iota
+	Trigger_WhenUnitDestroyed       EffectTrigger = iota
+	Trigger_WhenObjectiveDestroyed  EffectTrigger = iota
+	Trigger_WhenObjectiveEngaged    EffectTrigger = iota
+	Trigger_MAX                     EffectTrigger = iota
+)
+var TriggerNames [Trigger_MAX]string = [Trigger_MAX]string {
+	"None",
+	"Action",
+	"Constant",
+	"AfterOpponentTurnBegins",
+	"AfterRefresh",
+	"AfterUnitPlayed",
+	"AfterFocusedToStrike",
+	"AfterEdgeBattleWon",
+	"WhenEventPlayed",
+	"WhenDamageDealt",
+	"WhenUnitDestroyed",
+	"WhenObjectiveDestroyed",
+	"WhenObjectiveEngaged",
+}
+
+type EffectType int
+const (
+	Effect_AddFocus           EffectType = iota
+	Effect_RemoveFocus        EffectType = iota
+	Effect_DealDamage         EffectType = iota
+	Effect_RemoveDamage       EffectType = iota
+	Effect_RemoveAllDamage    EffectType = iota
+	Effect_AddShield          EffectType = iota
+	Effect_DiscardTokens      EffectType = iota // damage, focus, shields and enhancements
+	Effect_DrawCards          EffectType = iota
+	Effect_ReturnToHand       EffectType = iota
+	Effect_PutIntoPlay        EffectType = iota
+	Effect_Sacrifice          EffectType = iota
+	Effect_CancelEvent        EffectType = iota
+	Effect_CancelEdgeBattle   EffectType = iota
+	Effect_RedirectDamage     EffectType = iota
+	Effect_RedirectEngagement EffectType = iota
+	Effect_SearchObjective    EffectType = iota
+	Effect_GainCombatIcons    EffectType = iota
+	Effect_GainTargetedStrike EffectType = iota
+	Effect_ForceIcons         EffectType = iota // contributed to the Force struggle
+	Effect_ReduceCost         EffectType = iota // of the first matching card played each turn
+	Effect_RevealEdgeCard     EffectType = iota
+	Effect_ResolveFatesTwice  EffectType = iota
+	Effect_MAX                EffectType = iota
+)
+var EffectNames [Effect_MAX]string = [Effect_MAX]string {
+	"AddFocus",
+	"RemoveFocus",
+	"DealDamage",
+	"RemoveDamage",
+	"RemoveAllDamage",
+	"AddShield",
+	"DiscardTokens",
+	"DrawCards",
+	"ReturnToHand",
+	"PutIntoPlay",
+	"Sacrifice",
+	"CancelEvent",
+	"CancelEdgeBattle",
+	"RedirectDamage",
+	"RedirectEngagement",
+	"SearchObjective",
+	"GainCombatIcons",
+	"GainTargetedStrike",
+	"ForceIcons",
+	"ReduceCost",
+	"RevealEdgeCard",
+	"ResolveFatesTwice",
+}
+
+type EffectTarget int
+const (
+	Target_Self                   EffectTarget = iota
+	Target_Controller             EffectTarget = iota // the player controlling the card
+	Target_Opponent               EffectTarget = iota
+	Target_EnhancedCard           EffectTarget = iota
+	Target_TriggeringCard         EffectTarget = iota
+	Target_EngagedObjective       EffectTarget = iota
+	Target_FriendlyUnit           EffectTarget = iota
+	Target_EnemyUnit              EffectTarget = iota
+	Target_AnyUnit                EffectTarget = iota
+	Target_ParticipatingEnemyUnit EffectTarget = iota
+	Target_FriendlyObjective      EffectTarget = iota
+	Target_FriendlyDiscard        EffectTarget = iota
+	Target_MAX                    EffectTarget = iota
+)
+var TargetNames [Target_MAX]string = [Target_MAX]string {
+	"Self",
+	"Controller",
+	"Opponent",
+	"EnhancedCard",
+	"TriggeringCard",
+	"EngagedObjective",
+	"FriendlyUnit",
+	"EnemyUnit",
+	"AnyUnit",
+	"ParticipatingEnemyUnit",
+	"FriendlyObjective",
+	"FriendlyDiscard",
+}
+
+// Targets picked by the player when the ability resolves.
+func (t EffectTarget) IsChosen() bool {
+	return t >= Target_FriendlyUnit
+}
+
+type EffectCondition int
+const (
+	Condition_None               EffectCondition = iota
+	Condition_ForceWithLightSide EffectCondition = iota
+	Condition_ForceWithDarkSide  EffectCondition = iota
+	Condition_Attacking          EffectCondition = iota
+	Condition_YourTurn           EffectCondition = iota
+	Condition_MAX                EffectCondition = iota
+)
+var ConditionNames [Condition_MAX]string = [Condition_MAX]string {
+	"None",
+	"ForceWithLightSide",
+	"ForceWithDarkSide",
+	"Attacking",
+	"YourTurn",
+}
+
+type CardEffect struct {
+	Type      EffectType
+	Target    EffectTarget
+	Amount    int
+	Filter    SynergyInterface // cards the target must match, nil for any
+	Condition EffectCondition
+	Icons     CombatModifier   // for Effect_GainCombatIcons
+}
+
+func Effect(t EffectType, target EffectTarget, amount int) CardEffect {
+	return CardEffect{Type: t, Target: target, Amount: amount}
+}
+func CombatIconsEffect(target EffectTarget, icons CombatModifier) CardEffect {
+	return CardEffect{Type: Effect_GainCombatIcons, Target: target, Icons: icons}
+}
+func (e CardEffect) Only(filter SynergyInterface) CardEffect {
+	e.Filter = filter
+	return e
+}
+func (e CardEffect) If(condition EffectCondition) CardEffect {
+	e.Condition = condition
+	return e
+}
+
+func (e *CardEffect) Matches(c *Card) bool {
+	return e.Filter == nil || e.Filter.IsSynergizingWith(c)
+}
+
+// Scripted Abilities ---------------------------------------------------------
+
+func (a *CardAbility) With(trigger EffectTrigger, effects ...CardEffect) *CardAbility {
+	a.Trigger = trigger
+	a.Effects = effects
+	return a
+}
+func (a *CardAbility) Paying(costs ...CardEffect) *CardAbility {
+	a.Costs = costs
+	return a
+}
+func (a *CardAbility) When(filter SynergyInterface) *CardAbility {
+	a.TriggerFilter = filter
+	return a
+}
+func (a *CardAbility) LimitOncePerTurn() *CardAbility {
+	a.OncePerTurn = true
+	return a
+}
+
+func (a *CardAbility) IsScripted() bool {
+	return a.Trigger != Trigger_None
+}
+
+func (a *CardAbility) IsTriggeredBy(trigger EffectTrigger, triggering *Card) bool {
+	return a.Trigger == trigger && (a.TriggerFilter == nil || triggering != nil && a.TriggerFilter.IsSynergizingWith(triggering))
+}
+
+// The effect whose target is chosen when the ability resolves, nil if every
+// target is implied.
+func (a *CardAbility) ChosenTarget() *CardEffect {
+	for i := range a.Effects {
+		if a.Effects[i].Target.IsChosen() {
+			return &a.Effects[i]
+		}
+	}
+	return nil
+}
+
+func (c *Card) ScriptedAbilities(trigger EffectTrigger) []*CardAbility {
+	abilities := make([]*CardAbility, 0)
+	for _, ability := range c.Abilities {
+		if a, ok := ability.(*CardAbility); ok && a.Trigger == trigger {
+			abilities = append(abilities, a)
+		}
+	}
+	return abilities
+}
+
+// Effects of the card's constant abilities of the given type.
+func (c *Card) ConstantEffects(t EffectType) []CardEffect {
+	effects := make([]CardEffect, 0)
+	for _, a := range c.ScriptedAbilities(Trigger_Constant) {
+		for _, e := range a.Effects {
+			if e.Type == t {
+				effects = append(effects, e)
+			}
+		}
+	}
+	return effects
+}
+
+// Fate cards created with FateCancel or having an Effect_CancelEdgeBattle
+// action.
+func CardCancelsEdgeBattle(c *Card) bool {
+	if fate, ok := c.Type.(*FateCardType); ok && fate.CancelsEdgeBattle {
+		return true
+	}
+	for _, a := range c.ScriptedAbilities(Trigger_Action) {
+		for _, e := range a.Effects {
+			if e.Type == Effect_CancelEdgeBattle {
+				return true
+			}
+		}
+	}
+	return false
+}
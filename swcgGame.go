@@ -0,0 +1,1176 @@
+package swcg
+
+import "fmt"
+import "math/rand/v2"
+
+// Game Engine ----------------------------------------------------------------
+//
+// A two player game following the turn structure of the card game. Player 0
+// plays the dark side role and takes the first turn, player 1 the light side
+// role. Every turn goes through the balance, refresh, draw, deployment,
+// conflict and Force phases of the active player. The phases without
+// decisions are resolved automatically, the others wait for moves: the
+// player returned by ToAct picks one of LegalMoves and plays it with Apply.
+//
+// Card instances are referenced by their index in Game.Cards, so a game can
+// be cloned by copying slices, and all the randomness comes from the seed.
+
+const (
+	Player_Dark  = 0
+	Player_Light = 1
+	NoCard       = -1
+	NoPlayer     = -1
+)
+
+const (
+	ObjectivesInPlay   = 3
+	ObjectivesToWin    = 3
+	DeathStarDialToWin = 12
+	MaxTurns           = 100 // the dark side wins games going past it
+)
+
+type GamePhase int
+const (
+	Phase_Setup      GamePhase = iota
+	Phase_Balance    GamePhase = iota
+	Phase_Refresh    GamePhase = iota
+	Phase_Draw       GamePhase = iota
+	Phase_Deployment GamePhase = iota
+	Phase_Conflict   GamePhase = iota
+	Phase_Force      GamePhase = iota
+	Phase_GameOver   GamePhase = iota
+	Phase_MAX        GamePhase = iota
+)
+var PhaseNames [Phase_MAX]string = [Phase_MAX]string {
+	"Setup",
+	"Balance",
+	"Refresh",
+	"Draw",
+	"Deployment",
+	"Conflict",
+	"Force",
+	"GameOver",
+}
+
+// Decision points inside the phases.
+type GameStep int
+const (
+	Step_None             GameStep = iota
+	Step_Deploy           GameStep = iota
+	Step_Engage           GameStep = iota
+	Step_DeclareAttackers GameStep = iota
+	Step_DeclareDefenders GameStep = iota
+	Step_EdgeBattle       GameStep = iota
+	Step_ForceCommit      GameStep = iota
+	Step_MAX              GameStep = iota
+)
+var StepNames [Step_MAX]string = [Step_MAX]string {
+	"None",
+	"Deploy",
+	"Engage",
+	"DeclareAttackers",
+	"DeclareDefenders",
+	"EdgeBattle",
+	"ForceCommit",
+}
+
+type Zone int
+const (
+	Zone_CommandDeck   Zone = iota
+	Zone_ObjectiveDeck Zone = iota
+	Zone_Hand          Zone = iota
+	Zone_Objectives    Zone = iota
+	Zone_Play          Zone = iota
+	Zone_EdgeStack     Zone = iota
+	Zone_Discard       Zone = iota
+	Zone_Destroyed     Zone = iota
+	Zone_MAX           Zone = iota
+)
+var ZoneNames [Zone_MAX]string = [Zone_MAX]string {
+	"CommandDeck",
+	"ObjectiveDeck",
+	"Hand",
+	"Objectives",
+	"Play",
+	"EdgeStack",
+	"Discard",
+	"Destroyed",
+}
+
+// Card Instances -------------------------------------------------------------
+
+type CardInstance struct {
+	Id               int
+	Card             *Card
+	Owner            int
+	Zone             Zone
+	Damage           int
+	Focus            int
+	Shields          int
+	AttachedTo       int   // enhanced card, NoCard when in the play area
+	Enhancements     []int
+	CommittedToForce bool
+	UsedTurn         int   // last turn its limited ability or cost reduction was used
+	Revealed         bool  // edge card placed faceup, known to the opponent
+}
+
+func (ci *CardInstance) IsType(t CardType) bool {
+	return ci.Card.Type.GetType() == t
+}
+
+// Player State ---------------------------------------------------------------
+
+type PlayerState struct {
+	Deck                *Deck
+	Affiliation         *Affiliation
+	AffiliationFocus    int
+	CommandDeck         []int // top card first
+	ObjectiveDeck       []int
+	Hand                []int
+	Objectives          []int
+	Units               []int
+	Enhancements        []int // every enhancement in play, attached or not
+	EdgeStack           []int
+	Discard             []int
+	DestroyedObjectives []int
+	LimitedPlayed       bool
+}
+
+func (p *PlayerState) clone() *PlayerState {
+	c := *p
+	c.CommandDeck = append([]int(nil), p.CommandDeck...)
+	c.ObjectiveDeck = append([]int(nil), p.ObjectiveDeck...)
+	c.Hand = append([]int(nil), p.Hand...)
+	c.Objectives = append([]int(nil), p.Objectives...)
+	c.Units = append([]int(nil), p.Units...)
+	c.Enhancements = append([]int(nil), p.Enhancements...)
+	c.EdgeStack = append([]int(nil), p.EdgeStack...)
+	c.Discard = append([]int(nil), p.Discard...)
+	c.DestroyedObjectives = append([]int(nil), p.DestroyedObjectives...)
+	return &c
+}
+
+// Engagements ----------------------------------------------------------------
+
+type Engagement struct {
+	Objective   int
+	Attackers   []int
+	Defenders   []int
+	EdgeTurn    int
+	EdgePasses  int
+	EdgeBattles int
+	EdgeWinner  int // Edge_Attacker, Edge_Defender or Edge_None before the edge battle
+}
+
+func (e *Engagement) clone() *Engagement {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Attackers = append([]int(nil), e.Attackers...)
+	c.Defenders = append([]int(nil), e.Defenders...)
+	return &c
+}
+
+// Moves ----------------------------------------------------------------------
+
+type MoveType int
+const (
+	Move_Pass           MoveType = iota
+	Move_PlayCard       MoveType = iota
+	Move_Engage         MoveType = iota
+	Move_CommitAttacker MoveType = iota
+	Move_CommitDefender MoveType = iota
+	Move_EdgeCard       MoveType = iota
+	Move_CommitToForce  MoveType = iota
+	Move_UseAbility     MoveType = iota
+	Move_MAX            MoveType = iota
+)
+var MoveNames [Move_MAX]string = [Move_MAX]string {
+	"Pass",
+	"PlayCard",
+	"Engage",
+	"CommitAttacker",
+	"CommitDefender",
+	"EdgeCard",
+	"CommitToForce",
+	"UseAbility",
+}
+
+// Card and Target are card instance ids, or NoCard.
+type Move struct {
+	Type   MoveType
+	Player int
+	Card   int
+	Target int
+}
+
+func (m Move) String() string {
+	return fmt.Sprintf("%s(player %d, card %d, target %d)", MoveNames[m.Type], m.Player, m.Card, m.Target)
+}
+
+// Game -----------------------------------------------------------------------
+
+type Game struct {
+	Players           [2]*PlayerState
+	Cards             []CardInstance
+	Seed              uint64
+	Turn              int
+	Active            int
+	Phase             GamePhase
+	Step              GameStep
+	DeathStarDial     int
+	BalanceOfTheForce CardSide
+	Winner            int
+	Engagement        *Engagement
+	EngagedThisTurn   []int
+	ForceTurn         int
+	ForcePasses       int
+	MoveCount         int
+	pcg               rand.PCG
+}
+
+// Sets up a game: both decks shuffled, 3 objectives in play and an opening
+// hand for each player, then the dark side player's first turn starts.
+func NewGame(dark, light *Deck, seed uint64) *Game {
+	g := &Game{Seed: seed, Winner: NoPlayer, BalanceOfTheForce: Side_Light, Phase: Phase_Setup,
+		EngagedThisTurn: make([]int, 0), pcg: *rand.NewPCG(seed, 0x5357434721)}
+
+	for i, deck := range [2]*Deck{dark, light} {
+		p := &PlayerState{Deck: deck, Affiliation: deck.Affiliation}
+		for _, c := range deck.CommandDeck() {
+			p.CommandDeck = append(p.CommandDeck, g.newInstance(c, i, Zone_CommandDeck))
+		}
+		for _, c := range deck.ObjectiveDeck() {
+			p.ObjectiveDeck = append(p.ObjectiveDeck, g.newInstance(c, i, Zone_ObjectiveDeck))
+		}
+		g.shuffle(p.CommandDeck)
+		g.shuffle(p.ObjectiveDeck)
+		g.Players[i] = p
+	}
+	for i := range g.Players {
+		g.replaceObjectives(i)
+		g.drawUpTo(i, HandSize)
+	}
+
+	g.Active = Player_Dark
+	g.startTurn()
+	return g
+}
+
+func (g *Game) newInstance(c *Card, owner int, zone Zone) int {
+	id := len(g.Cards)
+	g.Cards = append(g.Cards, CardInstance{Id: id, Card: c, Owner: owner, Zone: zone, AttachedTo: NoCard})
+	return id
+}
+
+func (g *Game) rand() *rand.Rand {
+	return rand.New(&g.pcg)
+}
+
+func (g *Game) shuffle(ids []int) {
+	g.rand().Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+}
+
+func (g *Game) Clone() *Game {
+	c := *g
+	c.Cards = make([]CardInstance, len(g.Cards))
+	copy(c.Cards, g.Cards)
+	for i := range c.Cards {
+		if c.Cards[i].Enhancements != nil {
+			c.Cards[i].Enhancements = append([]int(nil), c.Cards[i].Enhancements...)
+		}
+	}
+	for i, p := range g.Players {
+		c.Players[i] = p.clone()
+	}
+	c.Engagement = g.Engagement.clone()
+	c.EngagedThisTurn = append([]int(nil), g.EngagedThisTurn...)
+	return &c
+}
+
+func (g *Game) Card(id int) *CardInstance {
+	return &g.Cards[id]
+}
+
+func (g *Game) IsOver() bool {
+	return g.Phase == Phase_GameOver
+}
+
+func (g *Game) Opponent(player int) int {
+	return 1-player
+}
+
+// Player expected to play the next move, NoPlayer once the game is over.
+func (g *Game) ToAct() int {
+	switch g.Step {
+	case Step_Deploy, Step_Engage, Step_DeclareAttackers:
+		return g.Active
+	case Step_DeclareDefenders:
+		return g.Opponent(g.Active)
+	case Step_EdgeBattle:
+		return g.Engagement.EdgeTurn
+	case Step_ForceCommit:
+		return g.ForceTurn
+	}
+	return NoPlayer
+}
+
+// Zone Management ------------------------------------------------------------
+
+func removeId(ids []int, id int) []int {
+	for i, v := range ids {
+		if v == id {
+			return append(ids[:i], ids[i+1:]...)
+		}
+	}
+	return ids
+}
+
+func containsId(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Game) drawUpTo(player int, handSize int) {
+	p := g.Players[player]
+	for len(p.Hand) < handSize && len(p.CommandDeck) > 0 {
+		id := p.CommandDeck[0]
+		p.CommandDeck = p.CommandDeck[1:]
+		g.Cards[id].Zone = Zone_Hand
+		p.Hand = append(p.Hand, id)
+	}
+}
+
+func (g *Game) DrawCards(player int, n int) {
+	g.drawUpTo(player, len(g.Players[player].Hand)+n)
+}
+
+func (g *Game) replaceObjectives(player int) {
+	p := g.Players[player]
+	for len(p.Objectives) < ObjectivesInPlay && len(p.ObjectiveDeck) > 0 {
+		id := p.ObjectiveDeck[0]
+		p.ObjectiveDeck = p.ObjectiveDeck[1:]
+		g.Cards[id].Zone = Zone_Objectives
+		p.Objectives = append(p.Objectives, id)
+	}
+}
+
+// Moves a card out of play (or out of the hand or edge stack) to its
+// owner's discard pile, with its enhancements and tokens.
+func (g *Game) Discard(id int) {
+	ci := &g.Cards[id]
+	p := g.Players[ci.Owner]
+	switch ci.Zone {
+	case Zone_Hand:      p.Hand = removeId(p.Hand, id)
+	case Zone_EdgeStack: p.EdgeStack = removeId(p.EdgeStack, id)
+	case Zone_Play:
+		p.Units = removeId(p.Units, id)
+		p.Enhancements = removeId(p.Enhancements, id)
+	}
+	if ci.AttachedTo != NoCard {
+		host := &g.Cards[ci.AttachedTo]
+		host.Enhancements = removeId(host.Enhancements, id)
+	}
+	for _, e := range append([]int(nil), ci.Enhancements...) {
+		g.Discard(e)
+	}
+	ci.Zone = Zone_Discard
+	ci.Damage, ci.Focus, ci.Shields, ci.AttachedTo, ci.Enhancements, ci.CommittedToForce, ci.Revealed = 0, 0, 0, NoCard, nil, false, false
+	p.Discard = append(p.Discard, id)
+}
+
+// Moves a card from its owner's discard pile or play area back to the hand.
+func (g *Game) ReturnToHand(id int) {
+	ci := &g.Cards[id]
+	p := g.Players[ci.Owner]
+	if ci.Zone == Zone_Play {
+		g.Discard(id)
+	}
+	p.Discard = removeId(p.Discard, id)
+	ci.Zone = Zone_Hand
+	p.Hand = append(p.Hand, id)
+}
+
+// Deals damage to a unit or an objective, shields preventing 1 damage each,
+// and destroys it once its damage reaches its health. The controller of a
+// unit can redirect some of the damage with its interrupts.
+func (g *Game) DealDamage(id int, damage int) {
+	ci := &g.Cards[id]
+	prevented := ci.Shields
+	if prevented > damage {
+		prevented = damage
+	}
+	ci.Shields -= prevented
+	damage -= prevented
+	if damage > 0 && ci.Zone == Zone_Play {
+		damage -= g.redirectDamage(id, damage, g.health(id)-ci.Damage)
+		if ci.Zone != Zone_Play {
+			return
+		}
+	}
+	ci.Damage += damage
+	if ci.Damage >= g.health(id) {
+		g.destroy(id)
+	}
+}
+
+func (g *Game) destroy(id int) {
+	ci := &g.Cards[id]
+	if ci.Zone == Zone_Objectives {
+		p := g.Players[ci.Owner]
+		for _, e := range append([]int(nil), ci.Enhancements...) {
+			g.Discard(e)
+		}
+		p.Objectives = removeId(p.Objectives, id)
+		p.DestroyedObjectives = append(p.DestroyedObjectives, id)
+		ci.Zone = Zone_Destroyed
+		g.checkVictory()
+		if !g.IsOver() && len(ci.Card.ScriptedAbilities(Trigger_WhenObjectiveDestroyed)) > 0 {
+			g.searchObjective(ci.Owner)
+		}
+		return
+	}
+	if ci.Zone == Zone_Play {
+		if a, source := g.findTriggeredAbility(Trigger_WhenUnitDestroyed, ci.Owner, id); a != nil {
+			g.resolveAbilityAuto(a, ci.Owner, source, id)
+		}
+		if ci.Zone == Zone_Play {
+			g.Discard(id)
+		}
+	}
+}
+
+// Puts the toughest objective of the objective deck into play, then
+// shuffles the objective deck.
+func (g *Game) searchObjective(player int) {
+	p := g.Players[player]
+	if len(p.ObjectiveDeck) == 0 {
+		return
+	}
+	best := p.ObjectiveDeck[0]
+	for _, id := range p.ObjectiveDeck {
+		if g.Cards[id].Card.Health > g.Cards[best].Card.Health {
+			best = id
+		}
+	}
+	p.ObjectiveDeck = removeId(p.ObjectiveDeck, best)
+	g.Cards[best].Zone = Zone_Objectives
+	p.Objectives = append(p.Objectives, best)
+	g.shuffle(p.ObjectiveDeck)
+}
+
+// Objective actually engaged when the defender redirects the engagement.
+func (g *Game) redirectEngagement(objective int) int {
+	defender := g.Opponent(g.Active)
+	for _, id := range g.Players[defender].Objectives {
+		if id == objective {
+			continue
+		}
+		for _, a := range g.Cards[id].Card.ScriptedAbilities(Trigger_WhenObjectiveEngaged) {
+			if g.canUseAbility(a, defender, id, objective) {
+				g.Cards[id].UsedTurn = g.Turn
+				return id
+			}
+		}
+	}
+	return objective
+}
+
+func (g *Game) checkVictory() {
+	switch {
+	case g.DeathStarDial >= DeathStarDialToWin:                           g.endGame(Player_Dark)
+	case len(g.Players[Player_Light].DestroyedObjectives) >= ObjectivesToWin: g.endGame(Player_Dark)
+	case len(g.Players[Player_Dark].DestroyedObjectives) >= ObjectivesToWin:  g.endGame(Player_Light)
+	}
+}
+
+func (g *Game) endGame(winner int) {
+	if g.Phase == Phase_GameOver {
+		return
+	}
+	g.Winner = winner
+	g.Phase = Phase_GameOver
+	g.Step = Step_None
+	g.Engagement = nil
+}
+
+// Resources ------------------------------------------------------------------
+
+// A card (or the affiliation, with Id NoCard) able to pay for cards by
+// being focused.
+type resourceProvider struct {
+	Id         int
+	Ressources int
+	Faction    CardFaction
+}
+
+func (g *Game) resourceProviders(player int) []resourceProvider {
+	p := g.Players[player]
+	providers := make([]resourceProvider, 0)
+	if p.Affiliation != nil && p.AffiliationFocus == 0 {
+		providers = append(providers, resourceProvider{NoCard, p.Affiliation.Ressources, p.Affiliation.Faction})
+	}
+	for _, zone := range [][]int{p.Objectives, p.Units, p.Enhancements} {
+		for _, id := range zone {
+			if ci := &g.Cards[id]; ci.Focus == 0 && ci.Card.Ressources > 0 {
+				providers = append(providers, resourceProvider{id, ci.Card.Ressources, ci.Card.Faction})
+			}
+		}
+	}
+	return providers
+}
+
+func (g *Game) AvailableResources(player int) int {
+	total := 0
+	for _, r := range g.resourceProviders(player) {
+		total += r.Ressources
+	}
+	return total
+}
+
+// Cheapest set of providers paying the cost, with at least one provider of
+// the card's faction for non neutral cards. Returns nil if it can't be paid.
+func (g *Game) payment(player int, c *Card) []resourceProvider {
+	cost := g.CardCost(player, c)
+	if cost <= 0 {
+		return []resourceProvider{}
+	}
+	providers := g.resourceProviders(player)
+	if len(providers) > 16 {
+		providers = providers[:16]
+	}
+	var best []resourceProvider
+	bestTotal := 0
+	for set := uint(1); set < 1<<uint(len(providers)); set++ {
+		total, matched, count := 0, c.Faction.IsNeutral(), 0
+		for i, r := range providers {
+			if set&(1<<uint(i)) != 0 {
+				total += r.Ressources
+				matched = matched || r.Faction == c.Faction
+				count++
+			}
+		}
+		if total < cost || !matched {
+			continue
+		}
+		if best == nil || total < bestTotal || total == bestTotal && count < len(best) {
+			best = make([]resourceProvider, 0, count)
+			for i, r := range providers {
+				if set&(1<<uint(i)) != 0 {
+					best = append(best, r)
+				}
+			}
+			bestTotal = total
+		}
+	}
+	return best
+}
+
+func (g *Game) CanPay(player int, c *Card) bool {
+	return g.payment(player, c) != nil
+}
+
+func (g *Game) pay(player int, c *Card) {
+	payment := g.payment(player, c)
+	for _, source := range g.costReductions(player, c) {
+		g.Cards[source].UsedTurn = g.Turn
+	}
+	for _, r := range payment {
+		if r.Id == NoCard {
+			g.Players[player].AffiliationFocus++
+		} else {
+			g.Cards[r.Id].Focus++
+		}
+	}
+}
+
+// Turn Structure -------------------------------------------------------------
+
+func (g *Game) startTurn() {
+	g.Turn++
+	g.EngagedThisTurn = g.EngagedThisTurn[:0]
+	g.Players[g.Active].LimitedPlayed = false
+	if g.Turn > MaxTurns {
+		g.endGame(Player_Dark)
+		return
+	}
+
+	g.triggerAbilities(Trigger_AfterOpponentTurnBegins, g.Opponent(g.Active), NoCard)
+	g.Phase = Phase_Balance
+	g.balancePhase()
+	if g.IsOver() {
+		return
+	}
+	g.Phase = Phase_Refresh
+	g.refreshPhase()
+	g.Phase = Phase_Draw
+	g.drawUpTo(g.Active, HandSize)
+	g.Phase = Phase_Deployment
+	g.Step = Step_Deploy
+}
+
+// The dark side advances the Death Star dial, twice if the Force is with it.
+// When the Force is with the light side, the light side player damages a
+// dark side objective, the closest one to destruction.
+func (g *Game) balancePhase() {
+	if g.Active == Player_Dark {
+		g.DeathStarDial++
+		if g.BalanceOfTheForce == Side_Dark {
+			g.DeathStarDial++
+		}
+		g.checkVictory()
+	} else if g.BalanceOfTheForce == Side_Light {
+		if target := g.mostDamagedObjective(Player_Dark); target != NoCard {
+			g.DealDamage(target, 1)
+		}
+	}
+}
+
+func (g *Game) mostDamagedObjective(player int) int {
+	best := NoCard
+	for _, id := range g.Players[player].Objectives {
+		remaining := g.Cards[id].Card.Health - g.Cards[id].Damage
+		if best == NoCard || remaining < g.Cards[best].Card.Health-g.Cards[best].Damage {
+			best = id
+		}
+	}
+	return best
+}
+
+// Removes 1 focus token from each of the active player's cards (2 for Elite
+// cards) and replaces the destroyed objectives.
+func (g *Game) refreshPhase() {
+	p := g.Players[g.Active]
+	p.AffiliationFocus = 0
+	for _, zone := range [][]int{p.Objectives, p.Units, p.Enhancements} {
+		for _, id := range zone {
+			ci := &g.Cards[id]
+			ci.Focus--
+			if KeywordPredicate(K_Elite)(ci.Card) {
+				ci.Focus--
+			}
+			if ci.Focus < 0 {
+				ci.Focus = 0
+			}
+		}
+	}
+	g.replaceObjectives(g.Active)
+	g.triggerAbilities(Trigger_AfterRefresh, g.Active, NoCard)
+}
+
+func (g *Game) startConflict() {
+	g.Phase = Phase_Conflict
+	g.Step = Step_Engage
+	g.Engagement = nil
+}
+
+func (g *Game) startForcePhase() {
+	g.Phase = Phase_Force
+	g.Step = Step_ForceCommit
+	g.ForceTurn = g.Active
+	g.ForcePasses = 0
+}
+
+// Units committed to the Force only count while unfocused. The winner of
+// the struggle brings the Balance of the Force to its side.
+func (g *Game) ForceIconsCommitted(player int) int {
+	icons := 0
+	for _, id := range g.Players[player].Units {
+		if ci := &g.Cards[id]; ci.CommittedToForce && ci.Focus == 0 {
+			icons += ci.Card.ForceIcons
+		}
+	}
+	return icons
+}
+
+// Force icons of the player in the Force struggle: its committed units and
+// the Force icons contributed by its cards in play.
+func (g *Game) ForceStruggleIcons(player int) int {
+	icons := g.ForceIconsCommitted(player)
+	for _, e := range g.constantEffects(player, Effect_ForceIcons) {
+		icons += e.Amount
+	}
+	return icons
+}
+
+func (g *Game) forceStruggle() {
+	dark, light := g.ForceStruggleIcons(Player_Dark), g.ForceStruggleIcons(Player_Light)
+	if dark > light {
+		g.BalanceOfTheForce = Side_Dark
+	} else if light > dark {
+		g.BalanceOfTheForce = Side_Light
+	}
+}
+
+func (g *Game) endTurn() {
+	g.forceStruggle()
+	g.Active = g.Opponent(g.Active)
+	g.startTurn()
+}
+
+// Legal Moves ----------------------------------------------------------------
+
+func (g *Game) LegalMoves() []Move {
+	player := g.ToAct()
+	if player == NoPlayer {
+		return nil
+	}
+	p := g.Players[player]
+	moves := make([]Move, 0)
+	pass := Move{Type: Move_Pass, Player: player, Card: NoCard, Target: NoCard}
+
+	switch g.Step {
+	case Step_Deploy:
+		for _, id := range p.Hand {
+			moves = append(moves, g.playMoves(player, id)...)
+		}
+		moves = append(moves, g.abilityMoves(player)...)
+		moves = append(moves, pass)
+
+	case Step_Engage:
+		moves = append(moves, g.actionMoves(player)...)
+		if len(g.eligibleUnits(player)) > 0 {
+			for _, id := range g.Players[g.Opponent(player)].Objectives {
+				if !containsId(g.EngagedThisTurn, id) {
+					moves = append(moves, Move{Type: Move_Engage, Player: player, Card: NoCard, Target: id})
+				}
+			}
+		}
+		moves = append(moves, pass)
+
+	case Step_DeclareAttackers, Step_DeclareDefenders:
+		moveType := Move_CommitAttacker
+		if g.Step == Step_DeclareDefenders {
+			moveType = Move_CommitDefender
+		}
+		for _, id := range g.eligibleUnits(player) {
+			moves = append(moves, Move{Type: moveType, Player: player, Card: id, Target: g.Engagement.Objective})
+		}
+		moves = append(moves, g.actionMoves(player)...)
+		if g.Step == Step_DeclareDefenders || len(g.Engagement.Attackers) > 0 {
+			moves = append(moves, pass)
+		}
+
+	case Step_EdgeBattle:
+		for _, id := range p.Hand {
+			moves = append(moves, Move{Type: Move_EdgeCard, Player: player, Card: id, Target: NoCard})
+		}
+		moves = append(moves, pass)
+
+	case Step_ForceCommit:
+		for _, id := range p.Units {
+			if !g.Cards[id].CommittedToForce {
+				moves = append(moves, Move{Type: Move_CommitToForce, Player: player, Card: id, Target: NoCard})
+			}
+		}
+		moves = append(moves, pass)
+	}
+	return moves
+}
+
+// Units able to join the current (or a new) engagement: unfocused and not
+// already participating.
+func (g *Game) eligibleUnits(player int) []int {
+	units := make([]int, 0)
+	for _, id := range g.Players[player].Units {
+		if g.Cards[id].Focus > 0 {
+			continue
+		}
+		if g.Engagement != nil && (containsId(g.Engagement.Attackers, id) || containsId(g.Engagement.Defenders, id)) {
+			continue
+		}
+		units = append(units, id)
+	}
+	return units
+}
+
+func (g *Game) playMoves(player int, id int) []Move {
+	ci := &g.Cards[id]
+	c := ci.Card
+	if c.Type.GetType() == CardType_Event {
+		return g.eventMoves(player, id)
+	}
+	if c.Type.GetType() != CardType_Unit && c.Type.GetType() != CardType_Enhancement {
+		return nil
+	}
+	if KeywordPredicate(K_Limited)(c) && g.Players[player].LimitedPlayed {
+		return nil
+	}
+	if !g.CanPay(player, c) {
+		return nil
+	}
+
+	moves := make([]Move, 0)
+	if c.Type.GetType() == CardType_Unit {
+		return append(moves, Move{Type: Move_PlayCard, Player: player, Card: id, Target: NoCard})
+	}
+	for _, target := range g.EnhancementTargets(player, c) {
+		moves = append(moves, Move{Type: Move_PlayCard, Player: player, Card: id, Target: target})
+	}
+	return moves
+}
+
+// Events from the hand and abilities of cards in play, available whenever
+// the player takes an action.
+func (g *Game) actionMoves(player int) []Move {
+	moves := make([]Move, 0)
+	for _, id := range g.Players[player].Hand {
+		moves = append(moves, g.eventMoves(player, id)...)
+	}
+	return append(moves, g.abilityMoves(player)...)
+}
+
+// An event is played for its first Action ability, once per possible target.
+func (g *Game) eventMoves(player int, id int) []Move {
+	c := g.Cards[id].Card
+	actions := c.ScriptedAbilities(Trigger_Action)
+	if c.Type.GetType() != CardType_Event || len(actions) == 0 || !g.CanPay(player, c) {
+		return nil
+	}
+	return g.targetedMoves(Move_PlayCard, actions[0], player, id)
+}
+
+func (g *Game) abilityMoves(player int) []Move {
+	moves := make([]Move, 0)
+	for _, id := range g.cardsInPlay(player) {
+		if actions := g.Cards[id].Card.ScriptedAbilities(Trigger_Action); len(actions) > 0 {
+			moves = append(moves, g.targetedMoves(Move_UseAbility, actions[0], player, id)...)
+		}
+	}
+	return moves
+}
+
+func (g *Game) targetedMoves(moveType MoveType, a *CardAbility, player int, id int) []Move {
+	if !g.canUseAbility(a, player, id, NoCard) {
+		return nil
+	}
+	chosen := a.ChosenTarget()
+	if chosen == nil {
+		return []Move{Move{Type: moveType, Player: player, Card: id, Target: NoCard}}
+	}
+	moves := make([]Move, 0)
+	for _, target := range g.targetCandidates(chosen, player) {
+		moves = append(moves, Move{Type: moveType, Player: player, Card: id, Target: target})
+	}
+	return moves
+}
+
+// Where an enhancement can be played: the play area (NoCard) for play area
+// enhancements, otherwise a friendly unit or objective its positive
+// synergies apply to (any friendly unit without such synergies). Cards with
+// the NoEnhancement keyword can't be enhanced.
+func (g *Game) EnhancementTargets(player int, c *Card) []int {
+	enhancement, ok := c.Type.(*EnhancementCardType)
+	targets := make([]int, 0)
+	if !ok {
+		return targets
+	}
+	unitSynergies := make(SynergyList, 0)
+	for _, s := range enhancement.Synergies {
+		if s.IsSynergizingWithPlayArea() {
+			return append(targets, NoCard)
+		}
+		if s.IsPositiveEffect() {
+			unitSynergies = append(unitSynergies, s)
+		}
+	}
+	p := g.Players[player]
+	for _, id := range append(append([]int(nil), p.Units...), p.Objectives...) {
+		host := g.Cards[id].Card
+		if KeywordPredicate(K_NoEnhancement)(host) {
+			continue
+		}
+		matches := len(unitSynergies) == 0 && host.Type.GetType() == CardType_Unit
+		for _, s := range unitSynergies {
+			matches = matches || s.IsSynergizingWith(host)
+		}
+		if matches {
+			targets = append(targets, id)
+		}
+	}
+	return targets
+}
+
+// Applying Moves -------------------------------------------------------------
+
+func (g *Game) IsLegal(m Move) bool {
+	for _, legal := range g.LegalMoves() {
+		if legal == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Game) Apply(m Move) error {
+	if !g.IsLegal(m) {
+		return fmt.Errorf("illegal move %v during %s/%s", m, PhaseNames[g.Phase], StepNames[g.Step])
+	}
+	g.MoveCount++
+	p := g.Players[m.Player]
+
+	switch m.Type {
+	case Move_PlayCard:
+		g.playCard(m.Player, m.Card, m.Target)
+
+	case Move_UseAbility:
+		a := g.Cards[m.Card].Card.ScriptedAbilities(Trigger_Action)[0]
+		g.resolveAbility(a, effectContext{Player: m.Player, Source: m.Card, Triggering: NoCard, Target: m.Target})
+
+	case Move_Engage:
+		objective := g.redirectEngagement(m.Target)
+		g.EngagedThisTurn = append(g.EngagedThisTurn, objective)
+		g.Engagement = &Engagement{Objective: objective, Attackers: make([]int, 0), Defenders: make([]int, 0), EdgeWinner: Edge_None}
+		g.Step = Step_DeclareAttackers
+
+	case Move_CommitAttacker:
+		g.Engagement.Attackers = append(g.Engagement.Attackers, m.Card)
+	case Move_CommitDefender:
+		g.Engagement.Defenders = append(g.Engagement.Defenders, m.Card)
+
+	case Move_EdgeCard:
+		p.Hand = removeId(p.Hand, m.Card)
+		p.EdgeStack = append(p.EdgeStack, m.Card)
+		g.Cards[m.Card].Zone = Zone_EdgeStack
+		g.Cards[m.Card].Revealed = len(p.EdgeStack) == 1 && g.revealsEdgeCard(g.Opponent(m.Player))
+		g.Engagement.EdgePasses = 0
+		g.Engagement.EdgeTurn = g.Opponent(m.Player)
+
+	case Move_CommitToForce:
+		g.Cards[m.Card].CommittedToForce = true
+
+	case Move_Pass:
+		g.pass(m.Player)
+	}
+	return nil
+}
+
+func (g *Game) playCard(player int, id int, target int) {
+	p := g.Players[player]
+	ci := &g.Cards[id]
+	g.pay(player, ci.Card)
+	if KeywordPredicate(K_Limited)(ci.Card) {
+		p.LimitedPlayed = true
+	}
+	if ci.IsType(CardType_Event) {
+		g.cancelEvent(player, id)
+		if ci.Zone == Zone_Hand {
+			a := ci.Card.ScriptedAbilities(Trigger_Action)[0]
+			g.resolveAbility(a, effectContext{Player: player, Source: id, Triggering: NoCard, Target: target})
+		}
+		if ci.Zone == Zone_Hand {
+			g.Discard(id)
+		}
+		return
+	}
+	p.Hand = removeId(p.Hand, id)
+	ci.Zone = Zone_Play
+	if ci.IsType(CardType_Unit) {
+		p.Units = append(p.Units, id)
+		g.triggerAbilities(Trigger_AfterUnitPlayed, player, id)
+		return
+	}
+	p.Enhancements = append(p.Enhancements, id)
+	if target != NoCard {
+		ci.AttachedTo = target
+		g.Cards[target].Enhancements = append(g.Cards[target].Enhancements, id)
+	}
+}
+
+func (g *Game) pass(player int) {
+	switch g.Step {
+	case Step_Deploy:
+		g.startConflict()
+	case Step_Engage:
+		g.startForcePhase()
+	case Step_DeclareAttackers:
+		g.Step = Step_DeclareDefenders
+	case Step_DeclareDefenders:
+		g.startEdgeBattle()
+	case Step_EdgeBattle:
+		g.Engagement.EdgePasses++
+		g.Engagement.EdgeTurn = g.Opponent(player)
+		if g.Engagement.EdgePasses >= 2 {
+			g.resolveEdgeBattle()
+		}
+	case Step_ForceCommit:
+		g.ForcePasses++
+		g.ForceTurn = g.Opponent(player)
+		if g.ForcePasses >= 2 {
+			g.endTurn()
+		}
+	}
+}
+
+// Conflict -------------------------------------------------------------------
+
+func (g *Game) startEdgeBattle() {
+	g.Step = Step_EdgeBattle
+	g.Engagement.EdgeTurn = g.Active
+	g.Engagement.EdgePasses = 0
+}
+
+func (g *Game) instanceCards(ids []int) []*Card {
+	cards := make([]*Card, len(ids))
+	for i, id := range ids {
+		cards[i] = g.Cards[id].Card
+	}
+	return cards
+}
+
+func (g *Game) discardEdgeStacks() {
+	for _, p := range g.Players {
+		for _, id := range append([]int(nil), p.EdgeStack...) {
+			g.Discard(id)
+		}
+	}
+}
+
+// Reveals the edge stacks. A canceled battle discards both stacks and starts
+// a new one, after MaxEdgeBattles the defender wins the edge. Otherwise the
+// fate cards resolve (twice with a participating unit allowing it), then the
+// edge winner's reactions and the combat.
+func (g *Game) resolveEdgeBattle() {
+	e := g.Engagement
+	players := [2]int{g.Active, g.Opponent(g.Active)}
+	result := ResolveEdgeBattle(
+		EdgeStack{Cards: g.instanceCards(g.Players[players[Edge_Attacker]].EdgeStack), Units: g.instanceCards(e.Attackers)},
+		EdgeStack{Cards: g.instanceCards(g.Players[players[Edge_Defender]].EdgeStack), Units: g.instanceCards(e.Defenders)})
+	fates := g.fateInstances(result, players)
+	g.discardEdgeStacks()
+	e.EdgeBattles++
+
+	if result.Canceled {
+		if e.EdgeBattles < MaxEdgeBattles {
+			g.startEdgeBattle()
+			return
+		}
+		result.Winner = Edge_Defender
+	} else {
+		for i, fate := range result.Fates {
+			player := players[fate.Side]
+			times := 1
+			if g.resolvesFatesTwice(g.participatingUnits(player)) {
+				times = 2
+			}
+			for _, a := range fate.Card.ScriptedAbilities(Trigger_Action) {
+				for t := 0; t < times && !g.IsOver() && g.canUseAbility(a, player, fates[i], NoCard); t++ {
+					g.resolveAbilityAuto(a, player, fates[i], NoCard)
+				}
+			}
+		}
+		if g.IsOver() {
+			return
+		}
+	}
+	e.EdgeWinner = result.Winner
+	g.triggerAbilities(Trigger_AfterEdgeBattleWon, players[e.EdgeWinner], NoCard)
+	if g.IsOver() {
+		return
+	}
+	g.pruneEngagement()
+	g.resolveCombat()
+}
+
+// Card instances of the resolved fate cards, matched in their owner's edge
+// stack.
+func (g *Game) fateInstances(result *EdgeBattleResult, players [2]int) []int {
+	ids := make([]int, len(result.Fates))
+	used := make(map[int]bool)
+	for i, fate := range result.Fates {
+		for _, id := range g.Players[players[fate.Side]].EdgeStack {
+			if g.Cards[id].Card == fate.Card && !used[id] {
+				ids[i], used[id] = id, true
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// Removes the participating units which left play before the combat.
+func (g *Game) pruneEngagement() {
+	inPlay := func(ids []int) []int {
+		units := make([]int, 0, len(ids))
+		for _, id := range ids {
+			if g.Cards[id].Zone == Zone_Play {
+				units = append(units, id)
+			}
+		}
+		return units
+	}
+	g.Engagement.Attackers = inPlay(g.Engagement.Attackers)
+	g.Engagement.Defenders = inPlay(g.Engagement.Defenders)
+}
+
+func (g *Game) combatUnits(ids []int) []CombatUnit {
+	units := make([]CombatUnit, len(ids))
+	for i, id := range ids {
+		ci := &g.Cards[id]
+		units[i] = CombatUnit{Card: ci.Card, Damage: ci.Damage, Focus: ci.Focus, Shields: ci.Shields, Modifiers: g.combatModifiers(id)}
+	}
+	return units
+}
+
+// Strikes through ResolveCombatWithEdge, then applies the outcome to the
+// card instances: striking units are focused, damage and focus tokens are
+// placed, the controllers respond to the damage and strikes, destroyed units
+// are discarded and the engaged objective takes the blast damage.
+func (g *Game) resolveCombat() {
+	e := g.Engagement
+	outcome := ResolveCombatWithEdge(g.combatUnits(e.Attackers), g.combatUnits(e.Defenders), e.EdgeWinner)
+
+	for side, ids := range [2][]int{e.Attackers, e.Defenders} {
+		for i, id := range ids {
+			unitOutcome := outcome.Sides[side][i]
+			ci := &g.Cards[id]
+			ci.Shields += -unitOutcome.ShieldsUsed
+			if unitOutcome.ShieldGained {
+				ci.Shields++
+			}
+			ci.Damage += unitOutcome.DamageTaken
+			ci.Focus += unitOutcome.FocusTaken
+			if unitOutcome.Struck {
+				ci.Focus++
+			}
+		}
+	}
+	destroyed := make([]int, 0)
+	for side, ids := range [2][]int{e.Attackers, e.Defenders} {
+		for i, id := range ids {
+			unitOutcome := outcome.Sides[side][i]
+			ci := &g.Cards[id]
+			if ci.Zone != Zone_Play {
+				continue // destroyed by an earlier response
+			}
+			if unitOutcome.DamageTaken > 0 {
+				redirected := g.redirectDamage(id, unitOutcome.DamageTaken, unitOutcome.Unit.Health()-unitOutcome.Unit.Damage)
+				ci.Damage -= redirected
+				unitOutcome.DamageTaken -= redirected
+				unitOutcome.Destroyed = unitOutcome.remainingHealth() <= 0
+			}
+			if unitOutcome.Destroyed {
+				destroyed = append(destroyed, id)
+			} else if unitOutcome.Struck && ci.Zone == Zone_Play {
+				g.reactToStrike(id)
+			}
+		}
+	}
+	for _, id := range destroyed {
+		if g.Cards[id].Zone == Zone_Play {
+			g.destroy(id)
+		}
+	}
+
+	if outcome.ObjectiveDamage > 0 {
+		g.DealDamage(e.Objective, outcome.ObjectiveDamage)
+	}
+	if !g.IsOver() {
+		g.startConflict()
+	}
+}
@@ -0,0 +1,471 @@
+package swcg
+
+// Effect Resolution ----------------------------------------------------------
+//
+// Executes the scripted card abilities during a game. Events are played for
+// their Action ability, fate cards resolve theirs when revealed in an edge
+// battle and in play cards with an Action ability can use it as a move.
+// Triggered abilities and the choices they need (targets, optional uses) are
+// resolved automatically for their controller, including the interrupts and
+// reactions played from the hand (see Responses below).
+
+type effectContext struct {
+	Player     int
+	Source     int // card instance with the ability
+	Triggering int // card instance which triggered the ability, or NoCard
+	Target     int // chosen target, or NoCard
+}
+
+func (g *Game) conditionHolds(condition EffectCondition, player int) bool {
+	switch condition {
+	case Condition_ForceWithLightSide: return g.BalanceOfTheForce == Side_Light
+	case Condition_ForceWithDarkSide:  return g.BalanceOfTheForce == Side_Dark
+	case Condition_Attacking:          return g.Engagement != nil && player == g.Active
+	case Condition_YourTurn:           return player == g.Active
+	}
+	return true
+}
+
+// Cards in play controlled by the player: objectives, units and
+// enhancements.
+func (g *Game) cardsInPlay(player int) []int {
+	p := g.Players[player]
+	ids := make([]int, 0, len(p.Objectives)+len(p.Units)+len(p.Enhancements))
+	ids = append(ids, p.Objectives...)
+	ids = append(ids, p.Units...)
+	return append(ids, p.Enhancements...)
+}
+
+func (g *Game) participatingUnits(player int) []int {
+	if g.Engagement == nil {
+		return nil
+	}
+	if player == g.Active {
+		return g.Engagement.Attackers
+	}
+	return g.Engagement.Defenders
+}
+
+// Whether the effect would change anything on the target.
+func (g *Game) isUsefulTarget(e *CardEffect, id int) bool {
+	ci := &g.Cards[id]
+	switch e.Type {
+	case Effect_RemoveFocus:                          return ci.Focus > 0
+	case Effect_RemoveDamage, Effect_RemoveAllDamage: return ci.Damage > 0
+	case Effect_DiscardTokens:                        return ci.Damage+ci.Focus > 0 || len(ci.Enhancements) > 0
+	}
+	return true
+}
+
+// Cards the effect's chosen target can be.
+func (g *Game) targetCandidates(e *CardEffect, player int) []int {
+	opponent := g.Opponent(player)
+	var ids []int
+	switch e.Target {
+	case Target_FriendlyUnit:           ids = g.Players[player].Units
+	case Target_EnemyUnit:              ids = g.Players[opponent].Units
+	case Target_AnyUnit:                ids = append(append([]int(nil), g.Players[player].Units...), g.Players[opponent].Units...)
+	case Target_ParticipatingEnemyUnit: ids = g.participatingUnits(opponent)
+	case Target_FriendlyObjective:      ids = g.Players[player].Objectives
+	case Target_FriendlyDiscard:        ids = g.Players[player].Discard
+	}
+	candidates := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if e.Matches(g.Cards[id].Card) && g.isUsefulTarget(e, id) {
+			candidates = append(candidates, id)
+		}
+	}
+	return candidates
+}
+
+// Fixed targets of the effect, NoCard when it targets a player.
+func (g *Game) effectTargets(e *CardEffect, ctx effectContext) []int {
+	switch e.Target {
+	case Target_Self:           return []int{ctx.Source}
+	case Target_TriggeringCard: return []int{ctx.Triggering}
+	case Target_EnhancedCard:
+		if host := g.Cards[ctx.Source].AttachedTo; host != NoCard {
+			return []int{host}
+		}
+	case Target_EngagedObjective:
+		if g.Engagement != nil {
+			return []int{g.Engagement.Objective}
+		}
+	case Target_Controller, Target_Opponent:
+		return []int{NoCard}
+	default:
+		if ctx.Target != NoCard && e.Matches(g.Cards[ctx.Target].Card) {
+			return []int{ctx.Target}
+		}
+	}
+	return nil
+}
+
+func (g *Game) canPayCosts(a *CardAbility, source int) bool {
+	ci := &g.Cards[source]
+	for _, cost := range a.Costs {
+		switch cost.Type {
+		case Effect_AddFocus:
+			if ci.Focus > 0 {
+				return false
+			}
+		case Effect_Sacrifice:
+			if ci.Zone != Zone_Play {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Whether the ability can be used now: its limit and costs allow it, at
+// least one of its effects applies and its chosen target (if any) exists.
+func (g *Game) canUseAbility(a *CardAbility, player int, source int, triggering int) bool {
+	if a.OncePerTurn && g.Cards[source].UsedTurn == g.Turn {
+		return false
+	}
+	if !g.canPayCosts(a, source) {
+		return false
+	}
+	if chosen := a.ChosenTarget(); chosen != nil {
+		return g.conditionHolds(chosen.Condition, player) && len(g.targetCandidates(chosen, player)) > 0
+	}
+	ctx := effectContext{Player: player, Source: source, Triggering: triggering, Target: NoCard}
+	for i := range a.Effects {
+		e := &a.Effects[i]
+		if !g.conditionHolds(e.Condition, player) {
+			continue
+		}
+		for _, id := range g.effectTargets(e, ctx) {
+			if id == NoCard || g.isUsefulTarget(e, id) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (g *Game) resolveAbility(a *CardAbility, ctx effectContext) {
+	if a.OncePerTurn {
+		g.Cards[ctx.Source].UsedTurn = g.Turn
+	}
+	for i := range a.Costs {
+		g.applyEffect(&a.Costs[i], ctx)
+	}
+	for i := range a.Effects {
+		if e := &a.Effects[i]; g.conditionHolds(e.Condition, ctx.Player) {
+			g.applyEffect(e, ctx)
+		}
+	}
+}
+
+func (g *Game) applyEffect(e *CardEffect, ctx effectContext) {
+	for _, id := range g.effectTargets(e, ctx) {
+		if g.IsOver() {
+			return
+		}
+		if id == NoCard {
+			g.applyPlayerEffect(e, ctx.Player)
+			continue
+		}
+		ci := &g.Cards[id]
+		switch e.Type {
+		case Effect_AddFocus:
+			ci.Focus += e.Amount
+		case Effect_RemoveFocus:
+			ci.Focus = max(0, ci.Focus-e.Amount)
+		case Effect_DealDamage:
+			g.DealDamage(id, e.Amount)
+		case Effect_RemoveDamage:
+			ci.Damage = max(0, ci.Damage-e.Amount)
+		case Effect_RemoveAllDamage:
+			ci.Damage = 0
+		case Effect_AddShield:
+			ci.Shields += e.Amount
+		case Effect_DiscardTokens:
+			ci.Damage, ci.Focus, ci.Shields = 0, 0, 0
+			for _, enhancement := range append([]int(nil), ci.Enhancements...) {
+				g.Discard(enhancement)
+			}
+		case Effect_ReturnToHand:
+			g.ReturnToHand(id)
+		case Effect_PutIntoPlay:
+			g.putIntoPlay(id)
+		case Effect_Sacrifice:
+			g.Discard(id)
+		case Effect_CancelEvent:
+			if ci.Zone == Zone_Hand {
+				g.Discard(id)
+			}
+		case Effect_RedirectDamage:
+			g.DealDamage(id, e.Amount)
+		}
+	}
+}
+
+func (g *Game) applyPlayerEffect(e *CardEffect, player int) {
+	switch e.Type {
+	case Effect_DrawCards:
+		g.DrawCards(player, e.Amount)
+	case Effect_ReturnToHand:
+		discard := g.Players[player].Discard
+		for i := 0; i < e.Amount && len(discard) > 0; i++ {
+			g.ReturnToHand(discard[len(discard)-1])
+			discard = g.Players[player].Discard
+		}
+	}
+}
+
+func (g *Game) putIntoPlay(id int) {
+	ci := &g.Cards[id]
+	p := g.Players[ci.Owner]
+	p.Discard = removeId(p.Discard, id)
+	ci.Zone = Zone_Play
+	p.Units = append(p.Units, id)
+}
+
+// Target picked for automatically resolved abilities: the enemy unit the
+// most hurt by the effect, or the friendly card it helps the most.
+func (g *Game) autoTarget(e *CardEffect, player int) int {
+	best, bestScore := NoCard, 0
+	for _, id := range g.targetCandidates(e, player) {
+		ci := &g.Cards[id]
+		score := ci.Card.Cost
+		switch e.Type {
+		case Effect_DealDamage:
+			if g.isLethal(id, e.Amount) {
+				score += 100
+			}
+		case Effect_AddFocus:
+			if ci.Focus == 0 {
+				score += 100
+			}
+		case Effect_RemoveFocus:
+			score += 10*ci.Focus
+		case Effect_RemoveDamage, Effect_RemoveAllDamage, Effect_DiscardTokens:
+			score += 10*(ci.Damage+ci.Focus)
+		case Effect_AddShield:
+			score -= 10*ci.Shields
+		}
+		if best == NoCard || score > bestScore {
+			best, bestScore = id, score
+		}
+	}
+	return best
+}
+
+func (g *Game) resolveAbilityAuto(a *CardAbility, player int, source int, triggering int) {
+	ctx := effectContext{Player: player, Source: source, Triggering: triggering, Target: NoCard}
+	if chosen := a.ChosenTarget(); chosen != nil {
+		ctx.Target = g.autoTarget(chosen, player)
+	}
+	g.resolveAbility(a, ctx)
+}
+
+// Whether the damage destroys the card, shields included.
+func (g *Game) isLethal(id int, damage int) bool {
+	ci := &g.Cards[id]
+	return g.health(id)-ci.Damage+ci.Shields <= damage
+}
+
+// Resolves the player's in play abilities with the trigger.
+func (g *Game) triggerAbilities(trigger EffectTrigger, player int, triggering int) {
+	var triggeringCard *Card
+	if triggering != NoCard {
+		triggeringCard = g.Cards[triggering].Card
+	}
+	for _, source := range g.cardsInPlay(player) {
+		for _, a := range g.Cards[source].Card.ScriptedAbilities(trigger) {
+			if g.IsOver() {
+				return
+			}
+			if zone := g.Cards[source].Zone; zone != Zone_Play && zone != Zone_Objectives {
+				break
+			}
+			if a.IsTriggeredBy(trigger, triggeringCard) && g.canUseAbility(a, player, source, triggering) {
+				g.resolveAbilityAuto(a, player, source, triggering)
+			}
+		}
+	}
+}
+
+// First in play ability of the player with the trigger usable now, the
+// card with that ability is returned with it.
+func (g *Game) findTriggeredAbility(trigger EffectTrigger, player int, triggering int) (*CardAbility, int) {
+	for _, source := range g.cardsInPlay(player) {
+		for _, a := range g.Cards[source].Card.ScriptedAbilities(trigger) {
+			if a.IsTriggeredBy(trigger, g.Cards[triggering].Card) && g.canUseAbility(a, player, source, triggering) {
+				return a, source
+			}
+		}
+	}
+	return nil, NoCard
+}
+
+// Responses ------------------------------------------------------------------
+//
+// Interrupts and reactions to another card: an event being played, damage
+// dealt to a unit or a unit focused to strike. They come from the player's
+// cards in play or from the events in its hand, paid for when played. The
+// events played in response don't give a chance to cancel them.
+
+// First response of the player to the triggering card: a usable ability of
+// its cards in play, else an event in its hand it can pay for. The card with
+// that ability is returned with it.
+func (g *Game) findResponse(trigger EffectTrigger, player int, triggering int) (*CardAbility, int) {
+	if a, source := g.findTriggeredAbility(trigger, player, triggering); a != nil {
+		return a, source
+	}
+	for _, id := range g.Players[player].Hand {
+		ci := &g.Cards[id]
+		if !ci.IsType(CardType_Event) || !g.CanPay(player, ci.Card) {
+			continue
+		}
+		for _, a := range ci.Card.ScriptedAbilities(trigger) {
+			if a.IsTriggeredBy(trigger, g.Cards[triggering].Card) && g.canUseAbility(a, player, id, triggering) {
+				return a, id
+			}
+		}
+	}
+	return nil, NoCard
+}
+
+// Resolves the response, an event from the hand being paid for and
+// discarded first so that it can't respond again to its own effects.
+func (g *Game) respond(a *CardAbility, player int, source int, triggering int, target int) {
+	if g.Cards[source].Zone == Zone_Hand {
+		g.pay(player, g.Cards[source].Card)
+		g.Discard(source)
+	}
+	g.resolveAbility(a, effectContext{Player: player, Source: source, Triggering: triggering, Target: target})
+}
+
+// The opponent of the player cancels the event being played whenever it
+// can.
+func (g *Game) cancelEvent(player int, event int) {
+	opponent := g.Opponent(player)
+	if a, source := g.findResponse(Trigger_WhenEventPlayed, opponent, event); a != nil {
+		g.respond(a, opponent, source, event, NoCard)
+	}
+}
+
+// Part of the damage dealt to a unit its controller deals to an enemy unit
+// instead, when that saves the unit or destroys the enemy unit. remaining
+// is the health the unit had left before taking the damage.
+func (g *Game) redirectDamage(id int, damage int, remaining int) int {
+	owner := g.Cards[id].Owner
+	a, source := g.findResponse(Trigger_WhenDamageDealt, owner, id)
+	if a == nil {
+		return 0
+	}
+	redirect := a.ChosenTarget()
+	if redirect == nil || redirect.Type != Effect_RedirectDamage {
+		return 0
+	}
+	amount := min(redirect.Amount, damage)
+	enemyDamage := *redirect
+	enemyDamage.Type, enemyDamage.Target, enemyDamage.Amount = Effect_DealDamage, Target_EnemyUnit, amount
+	target := g.autoTarget(&enemyDamage, owner)
+	saves := damage >= remaining && damage-amount < remaining
+	if target == NoCard || !saves && !g.isLethal(target, amount) {
+		return 0
+	}
+	g.respond(a, owner, source, id, target)
+	return amount
+}
+
+// Reactions of the unit's controller after it was focused to strike.
+func (g *Game) reactToStrike(id int) {
+	owner := g.Cards[id].Owner
+	if a, source := g.findResponse(Trigger_AfterFocusedToStrike, owner, id); a != nil {
+		g.respond(a, owner, source, id, NoCard)
+	}
+}
+
+// Constant Effects -----------------------------------------------------------
+
+func (g *Game) constantEffects(player int, t EffectType) []CardEffect {
+	effects := make([]CardEffect, 0)
+	for _, id := range g.cardsInPlay(player) {
+		effects = append(effects, g.Cards[id].Card.ConstantEffects(t)...)
+	}
+	return effects
+}
+
+// Cost of the card for the player, after the reductions not used yet this
+// turn.
+func (g *Game) CardCost(player int, c *Card) int {
+	cost := c.Cost
+	for _, source := range g.costReductions(player, c) {
+		for _, e := range g.Cards[source].Card.ConstantEffects(Effect_ReduceCost) {
+			if e.Matches(c) {
+				cost -= e.Amount
+			}
+		}
+	}
+	return max(0, cost)
+}
+
+func (g *Game) costReductions(player int, c *Card) []int {
+	sources := make([]int, 0)
+	for _, id := range g.cardsInPlay(player) {
+		if g.Cards[id].UsedTurn == g.Turn {
+			continue
+		}
+		for _, e := range g.Cards[id].Card.ConstantEffects(Effect_ReduceCost) {
+			if e.Matches(c) {
+				sources = append(sources, id)
+				break
+			}
+		}
+	}
+	return sources
+}
+
+// Health of the card, with the bonus given by its enhancements.
+func (g *Game) health(id int) int {
+	health := g.Cards[id].Card.Health
+	for _, m := range g.combatModifiers(id) {
+		health += m.Health
+	}
+	return health
+}
+
+// Combat icons and keywords given to the unit by its enhancements.
+func (g *Game) combatModifiers(id int) []CombatModifier {
+	modifiers := make([]CombatModifier, 0)
+	for _, enhancement := range g.Cards[id].Enhancements {
+		for _, a := range g.Cards[enhancement].Card.ScriptedAbilities(Trigger_Constant) {
+			for _, e := range a.Effects {
+				if e.Target != Target_EnhancedCard {
+					continue
+				}
+				switch e.Type {
+				case Effect_GainCombatIcons:    modifiers = append(modifiers, e.Icons)
+				case Effect_GainTargetedStrike: modifiers = append(modifiers, CombatModifier{TargetedStrike: true})
+				}
+			}
+		}
+	}
+	return modifiers
+}
+
+// Whether a participating unit of the player makes its opponent place the
+// first card of its edge stack faceup.
+func (g *Game) revealsEdgeCard(player int) bool {
+	for _, id := range g.participatingUnits(player) {
+		if len(g.Cards[id].Card.ConstantEffects(Effect_RevealEdgeCard)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Game) resolvesFatesTwice(units []int) bool {
+	for _, id := range units {
+		if len(g.Cards[id].Card.ConstantEffects(Effect_ResolveFatesTwice)) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package swcg
+
+import "math/rand/v2"
+import "testing"
+
+// Puts a new instance of the card in play for the player.
+func putInPlay(g *Game, player int, c *Card) int {
+	zone := Zone_Play
+	if c.Type.GetType() == CardType_Objective {
+		zone = Zone_Objectives
+	}
+	id := g.newInstance(c, player, zone)
+	p := g.Players[player]
+	switch c.Type.GetType() {
+	case CardType_Objective:   p.Objectives = append(p.Objectives, id)
+	case CardType_Enhancement: p.Enhancements = append(p.Enhancements, id)
+	default:                   p.Units = append(p.Units, id)
+	}
+	return id
+}
+
+func TestCardCostReductionsMatchTheCard(t *testing.T) {
+	dark, light := testDecks(t)
+	g := NewGame(dark, light, 1)
+	g.Players[Player_Dark].Objectives = nil // with their own reductions
+	putInPlay(g, Player_Dark, &Card{Name: "Discounts", Type: Objective(false), Abilities: AbilityList{
+		ConstantEffect("Units cost 1 less, enhancements 2 less.", nil).With(Trigger_Constant,
+			Effect(Effect_ReduceCost, Target_Controller, 1).Only(TypeSynergy(CardType_Unit, true)),
+			Effect(Effect_ReduceCost, Target_Controller, 2).Only(TypeSynergy(CardType_Enhancement, true)))}})
+
+	tests := []struct {
+		card     *Card
+		expected int
+	}{
+		{&Card{Name: "Unit", Type: Type(CardType_Unit), Cost: 3}, 2},
+		{&Card{Name: "Enhancement", Type: Enhancement(nil), Cost: 3}, 1},
+		{&Card{Name: "Event", Type: Type(CardType_Event), Cost: 3}, 3},
+		{&Card{Name: "Cheap enhancement", Type: Enhancement(nil), Cost: 1}, 0},
+	}
+	for _, test := range tests {
+		if cost := g.CardCost(Player_Dark, test.card); cost != test.expected {
+			t.Errorf("%s costs %d, expected %d", test.card.Name, cost, test.expected)
+		}
+	}
+}
+
+// A game with empty hands and no units, so that only the tested cards can
+// respond.
+func responseGame(t *testing.T) *Game {
+	dark, light := testDecks(t)
+	g := NewGame(dark, light, 1)
+	for _, p := range g.Players {
+		p.Hand, p.Units, p.Enhancements = nil, nil, nil
+	}
+	return g
+}
+
+func putInHand(g *Game, player int, c *Card) int {
+	id := g.newInstance(c, player, Zone_Hand)
+	g.Players[player].Hand = append(g.Players[player].Hand, id)
+	return id
+}
+
+func TestEventsCanBeCanceled(t *testing.T) {
+	db := CreateDB()
+	draw := &Card{Name: "Draw", Faction: Faction_LightNeutral, Type: Type(CardType_Event), Abilities: AbilityList{
+		Action("Draw 2 cards.", nil).With(Trigger_Action, Effect(Effect_DrawCards, Target_Controller, 2))}}
+	for _, canceled := range []bool{false, true} {
+		g := responseGame(t)
+		var counter int
+		if canceled {
+			counter = putInHand(g, Player_Dark, testCard(db, "Counter-Stroke"))
+		}
+		event := putInHand(g, Player_Light, draw)
+		g.playCard(Player_Light, event, NoCard)
+		if g.Cards[event].Zone != Zone_Discard {
+			t.Errorf("canceled %v: the event should be discarded", canceled)
+		}
+		drawn := 2
+		if canceled {
+			drawn = 0
+			if g.Cards[counter].Zone != Zone_Discard {
+				t.Errorf("Counter-Stroke should be discarded once played")
+			}
+		}
+		if len(g.Players[Player_Light].Hand) != drawn {
+			t.Errorf("canceled %v: drew %d cards, expected %d", canceled, len(g.Players[Player_Light].Hand), drawn)
+		}
+	}
+}
+
+func TestDamageIsRedirected(t *testing.T) {
+	db := CreateDB()
+	tests := []struct {
+		name           string
+		health, damage int
+		enemyHealth    int
+		redirected     bool
+	}{
+		{"saves the unit", 3, 2, 3, true},
+		{"destroys the enemy", 5, 0, 1, true},
+		{"no gain", 5, 0, 3, false},
+	}
+	for _, test := range tests {
+		g := responseGame(t)
+		deflection := putInHand(g, Player_Dark, testCard(db, "Lightsaber Deflection"))
+		unit := putInPlay(g, Player_Dark, &Card{Name: "Unit", Type: Type(CardType_Unit), Health: test.health})
+		enemy := putInPlay(g, Player_Light, &Card{Name: "Enemy", Type: Type(CardType_Unit), Health: test.enemyHealth})
+		g.Cards[unit].Damage = test.damage
+		g.DealDamage(unit, 1)
+		if redirected := g.Cards[deflection].Zone == Zone_Discard; redirected != test.redirected {
+			t.Errorf("%s: redirected %v, expected %v", test.name, redirected, test.redirected)
+		}
+		damage, enemyDamage := test.damage+1, 0
+		if test.redirected {
+			damage, enemyDamage = test.damage, 1
+		}
+		if g.Cards[unit].Damage != damage || g.Cards[unit].Zone != Zone_Play {
+			t.Errorf("%s: the unit has %d damage, expected %d", test.name, g.Cards[unit].Damage, damage)
+		}
+		if enemyDamage >= test.enemyHealth {
+			if g.Cards[enemy].Zone != Zone_Discard {
+				t.Errorf("%s: the enemy should be destroyed", test.name)
+			}
+		} else if g.Cards[enemy].Damage != enemyDamage {
+			t.Errorf("%s: the enemy has %d damage, expected %d", test.name, g.Cards[enemy].Damage, enemyDamage)
+		}
+	}
+}
+
+func TestReactionAfterFocusedToStrike(t *testing.T) {
+	db := CreateDB()
+	g := responseGame(t)
+	strike := putInHand(g, Player_Dark, testCard(db, "Double Strike"))
+	vehicle := putInPlay(g, Player_Dark, &Card{Name: "Vehicle", Type: Type(CardType_Unit), Health: 2})
+	character := putInPlay(g, Player_Dark, &Card{Name: "Character", Type: Type(CardType_Unit), Health: 2, Abilities: AbilityList{Trait(Trait_Character)}})
+	g.Cards[vehicle].Focus, g.Cards[character].Focus = 1, 1
+
+	g.reactToStrike(vehicle)
+	if g.Cards[vehicle].Focus != 1 || g.Cards[strike].Zone != Zone_Hand {
+		t.Errorf("Double Strike only readies Character units")
+	}
+	g.reactToStrike(character)
+	if g.Cards[character].Focus != 0 || g.Cards[strike].Zone != Zone_Discard {
+		t.Errorf("Double Strike should remove the focus token of the Character unit")
+	}
+}
+
+func TestRedirectedDamageCanBeRedirectedBack(t *testing.T) {
+	db := CreateDB()
+	g := responseGame(t)
+	deflections := [2]int{}
+	for player := range deflections {
+		deflections[player] = putInHand(g, player, testCard(db, "Lightsaber Deflection"))
+	}
+	unit := putInPlay(g, Player_Dark, &Card{Name: "Unit", Type: Type(CardType_Unit), Health: 1})
+	enemy := putInPlay(g, Player_Light, &Card{Name: "Enemy", Type: Type(CardType_Unit), Health: 1})
+	g.DealDamage(unit, 1)
+	for player, id := range deflections {
+		if g.Cards[id].Zone != Zone_Discard {
+			t.Errorf("player %d should have played its Lightsaber Deflection", player)
+		}
+	}
+	if g.Cards[unit].Zone != Zone_Discard || g.Cards[enemy].Zone != Zone_Play {
+		t.Errorf("the damage should end on the first unit")
+	}
+}
+
+func TestObiWanRevealsTheFirstEdgeCard(t *testing.T) {
+	db := CreateDB()
+	for _, obiWan := range []bool{true, false} {
+		g := responseGame(t)
+		attacker := putInPlay(g, Player_Dark, testCard(db, "Yoda"))
+		if obiWan {
+			attacker = putInPlay(g, Player_Dark, testCard(db, "Obi-Wan Kenobi"))
+		}
+		first := putInHand(g, Player_Light, testCard(db, "Counter-Stroke"))
+		second := putInHand(g, Player_Light, testCard(db, "Double Strike"))
+		g.Phase, g.Step = Phase_Conflict, Step_EdgeBattle
+		g.Engagement = &Engagement{Objective: g.Players[Player_Light].Objectives[0], Attackers: []int{attacker}, Defenders: []int{},
+			EdgeTurn: Player_Light, EdgeWinner: Edge_None}
+		for _, id := range []int{first, second} {
+			g.Engagement.EdgeTurn = Player_Light
+			if err := g.Apply(Move{Type: Move_EdgeCard, Player: Player_Light, Card: id, Target: NoCard}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if g.Cards[first].Revealed != obiWan || g.Cards[second].Revealed {
+			t.Errorf("Obi-Wan %v: first card revealed %v, second %v", obiWan, g.Cards[first].Revealed, g.Cards[second].Revealed)
+		}
+		if !obiWan {
+			continue
+		}
+		for seed := uint64(0); seed < 10; seed++ {
+			d := g.Determinize(Player_Dark, rand.New(rand.NewPCG(seed, 0)))
+			if stack := d.Players[Player_Light].EdgeStack; len(stack) != 2 || stack[0] != first {
+				t.Errorf("seed %d: the revealed card should stay first in the edge stack, got %v", seed, stack)
+			}
+		}
+	}
+}
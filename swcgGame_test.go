@@ -0,0 +1,66 @@
+package swcg
+
+import "reflect"
+import "testing"
+
+func testDecks(t *testing.T) (*Deck, *Deck) {
+	_, cache := AnalyzeDB(CreateDB())
+	dark, err := CreateDeck(cache, Faction_Jedi, 1, 1, 2, 2, 3, 3, 4, 5, 6, 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	light, err := CreateDeck(cache, Faction_Jedi, 1, 1, 2, 2, 3, 3, 4, 4, 5, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dark, light
+}
+
+// Card ids of every zone, the full visible state of a game.
+func gameZones(g *Game) [][]int {
+	zones := make([][]int, 0)
+	for _, p := range g.Players {
+		zones = append(zones, p.CommandDeck, p.Hand, p.Objectives, p.Units, p.Enhancements, p.Discard, p.DestroyedObjectives)
+	}
+	return zones
+}
+
+func TestPlayGameIsDeterministic(t *testing.T) {
+	dark, light := testDecks(t)
+	for seed := uint64(1); seed <= 5; seed++ {
+		games := [2]*Game{}
+		for i := range games {
+			g, err := PlayGame(dark, light, [2]Player{NewRandomPlayer(seed), NewGreedyPlayer(seed)}, seed)
+			if err != nil {
+				t.Fatalf("seed %d: %v", seed, err)
+			}
+			if !g.IsOver() || g.Winner == NoPlayer {
+				t.Fatalf("seed %d: the game ended without a winner", seed)
+			}
+			games[i] = g
+		}
+		a, b := games[0], games[1]
+		if a.Winner != b.Winner || a.Turn != b.Turn || a.MoveCount != b.MoveCount || a.DeathStarDial != b.DeathStarDial {
+			t.Errorf("seed %d: games differ: winner %d/%d, turn %d/%d, moves %d/%d", seed, a.Winner, b.Winner, a.Turn, b.Turn, a.MoveCount, b.MoveCount)
+		}
+		if !reflect.DeepEqual(gameZones(a), gameZones(b)) {
+			t.Errorf("seed %d: games end with different cards in the zones", seed)
+		}
+	}
+}
+
+func TestNewGameSetup(t *testing.T) {
+	dark, light := testDecks(t)
+	g := NewGame(dark, light, 3)
+	if g.Turn != 1 || g.Active != Player_Dark || g.Step != Step_Deploy || g.ToAct() != Player_Dark {
+		t.Errorf("the dark side should deploy first, got turn %d, active %d, step %s", g.Turn, g.Active, StepNames[g.Step])
+	}
+	for i, p := range g.Players {
+		if len(p.Hand) != HandSize || len(p.Objectives) != ObjectivesInPlay {
+			t.Errorf("player %d starts with %d cards and %d objectives", i, len(p.Hand), len(p.Objectives))
+		}
+	}
+	if g.DeathStarDial != 1 {
+		t.Errorf("the first balance phase should advance the dial to 1, got %d", g.DeathStarDial)
+	}
+}
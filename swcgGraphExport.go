@@ -0,0 +1,255 @@
+package swcg
+
+import "bufio"
+import "encoding/xml"
+import "fmt"
+import "io"
+import "sort"
+import "strconv"
+import "strings"
+
+// Graph Export ---------------------------------------------------------------
+//
+// Synergy graphs written as DOT (Graphviz) and GEXF (Gephi). Nodes are
+// coloured by faction and shaped by card type, positive edges are solid
+// and synergies against the opponent's cards dashed.
+
+var FactionColors [Faction_MAX]string = [Faction_MAX]string {
+	"#3b7dd8", // Jedi
+	"#e07b39", // Rebel Alliance
+	"#a8782f", // Smugglers
+	"#c8c8c8", // Light Neutral
+	"#b22222", // Sith
+	"#4d4d4d", // Imperial Navy
+	"#6b8e23", // Scum and Villany
+	"#808080", // Dark Neutral
+}
+
+var dotShapes [CardType_MAX]string = [CardType_MAX]string {
+	"ellipse",        // Unit
+	"box",            // Event
+	"doubleoctagon",  // Objective
+	"diamond",        // Fate
+	"hexagon",        // Enhancement
+}
+
+// GEXF only knows disc, square, triangle and diamond.
+var gexfShapes [CardType_MAX]string = [CardType_MAX]string {
+	"disc",
+	"square",
+	"diamond",
+	"triangle",
+	"square",
+}
+
+type exportNode struct {
+	Id      string
+	Label   string
+	Faction CardFaction
+	Type    CardType
+}
+
+type exportEdge struct {
+	From     string
+	To       string
+	Positive bool
+	Weight   int
+}
+
+type exportGraph struct {
+	Name  string
+	Nodes []exportNode
+	Edges []exportEdge
+}
+
+func cardNodeId(number int) string { return "card" + strconv.Itoa(number) }
+func setNodeId(id int) string      { return "set" + strconv.Itoa(id) }
+
+func (graph *SynergyGraph) export(cache *DataCache) *exportGraph {
+	g := &exportGraph{Name: "card_synergies"}
+	for _, n := range graph.Nodes {
+		c := (*cache.CardMap)[n]
+		t := CardType_Unit
+		if c.Type != nil {
+			t = c.Type.GetType()
+		}
+		g.Nodes = append(g.Nodes, exportNode{Id: cardNodeId(n), Label: c.Name, Faction: c.Faction, Type: t})
+	}
+	for _, e := range graph.Edges() {
+		g.Edges = append(g.Edges, exportEdge{From: cardNodeId(e.From), To: cardNodeId(e.To), Positive: e.Positive, Weight: 1})
+	}
+	return g
+}
+
+func (graph *SynergyGraph) WriteDOT(w io.Writer, cache *DataCache) error {
+	return graph.export(cache).writeDOT(w)
+}
+
+func (graph *SynergyGraph) WriteGEXF(w io.Writer, cache *DataCache) error {
+	return graph.export(cache).writeGEXF(w)
+}
+
+// Objective Set View ---------------------------------------------------------
+
+// Synergies aggregated by objective set: the weight of an edge is the number
+// of card edges going from the cards of one set to the cards of another.
+// Synergies within a set are left out.
+type ObjectiveSetGraph struct {
+	Sets  []int // set ids, sorted
+	Edges []ObjectiveSetEdge
+	cache *DataCache
+}
+
+type ObjectiveSetEdge struct {
+	From     int
+	To       int
+	Positive bool
+	Weight   int
+}
+
+func (graph *SynergyGraph) ObjectiveSetView(cache *DataCache) *ObjectiveSetGraph {
+	view := &ObjectiveSetGraph{Sets: make([]int, 0, len(*cache.SetMap)), cache: cache}
+	setsOf := make(map[int][]int) // card number -> set ids
+	for id, set := range *cache.SetMap {
+		view.Sets = append(view.Sets, id)
+		for _, c := range set {
+			if c == nil {
+				continue
+			}
+			// a card can appear more than once in the same set
+			if sets := setsOf[c.Number]; len(sets) == 0 || sets[len(sets)-1] != id {
+				setsOf[c.Number] = append(sets, id)
+			}
+		}
+	}
+	sort.Ints(view.Sets)
+
+	type key struct {
+		from, to int
+		positive bool
+	}
+	weights := make(map[key]int)
+	for _, e := range graph.Edges() {
+		for _, from := range setsOf[e.From] {
+			for _, to := range setsOf[e.To] {
+				if from != to {
+					weights[key{from, to, e.Positive}]++
+				}
+			}
+		}
+	}
+	for k, weight := range weights {
+		view.Edges = append(view.Edges, ObjectiveSetEdge{From: k.from, To: k.to, Positive: k.positive, Weight: weight})
+	}
+	sort.Slice(view.Edges, func(i, j int) bool {
+		a, b := view.Edges[i], view.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Positive && !b.Positive
+	})
+	return view
+}
+
+func (view *ObjectiveSetGraph) export() *exportGraph {
+	g := &exportGraph{Name: "objective_set_synergies"}
+	for _, id := range view.Sets {
+		node := exportNode{Id: setNodeId(id), Label: "Set " + strconv.Itoa(id), Faction: Faction_LightNeutral, Type: CardType_Objective}
+		if objective := (*view.cache.SetMap)[id][0]; objective != nil {
+			node.Label += ": " + objective.Name
+			node.Faction = objective.Faction
+		}
+		g.Nodes = append(g.Nodes, node)
+	}
+	for _, e := range view.Edges {
+		g.Edges = append(g.Edges, exportEdge{From: setNodeId(e.From), To: setNodeId(e.To), Positive: e.Positive, Weight: e.Weight})
+	}
+	return g
+}
+
+func (view *ObjectiveSetGraph) WriteDOT(w io.Writer) error  { return view.export().writeDOT(w) }
+func (view *ObjectiveSetGraph) WriteGEXF(w io.Writer) error { return view.export().writeGEXF(w) }
+
+// Writers --------------------------------------------------------------------
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+func (g *exportGraph) writeDOT(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "digraph %s {\n", g.Name)
+	fmt.Fprintln(out, "\tnode [style=filled, fontname=\"Helvetica\"];")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(out, "\t%s [label=%s, fillcolor=%s, shape=%s];\n",
+			n.Id, dotQuote(n.Label), dotQuote(FactionColors[n.Faction]), dotShapes[n.Type])
+	}
+	for _, e := range g.Edges {
+		style := "style=solid, color=\"#2e8b57\""
+		if !e.Positive {
+			style = "style=dashed, color=\"#b22222\", arrowhead=tee"
+		}
+		if e.Weight > 1 {
+			style += fmt.Sprintf(", label=\"%d\", penwidth=%d", e.Weight, min(e.Weight, 8))
+		}
+		fmt.Fprintf(out, "\t%s -> %s [%s];\n", e.From, e.To, style)
+	}
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func hexColor(color string) (int, int, int) {
+	rgb, _ := strconv.ParseUint(strings.TrimPrefix(color, "#"), 16, 32)
+	return int(rgb >> 16 & 0xff), int(rgb >> 8 & 0xff), int(rgb & 0xff)
+}
+
+func (g *exportGraph) writeGEXF(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(out, `<gexf xmlns="http://gexf.net/1.3" xmlns:viz="http://gexf.net/1.3/viz" version="1.3">`)
+	fmt.Fprintf(out, "  <meta><description>%s</description></meta>\n", g.Name)
+	fmt.Fprintln(out, `  <graph defaultedgetype="directed">`)
+	fmt.Fprintln(out, `    <attributes class="node">`)
+	fmt.Fprintln(out, `      <attribute id="faction" title="Faction" type="string"/>`)
+	fmt.Fprintln(out, `      <attribute id="type" title="Type" type="string"/>`)
+	fmt.Fprintln(out, `    </attributes>`)
+	fmt.Fprintln(out, `    <attributes class="edge">`)
+	fmt.Fprintln(out, `      <attribute id="effect" title="Effect" type="string"/>`)
+	fmt.Fprintln(out, `    </attributes>`)
+	fmt.Fprintln(out, `    <nodes>`)
+	for _, n := range g.Nodes {
+		r, gr, b := hexColor(FactionColors[n.Faction])
+		fmt.Fprintf(out, "      <node id=\"%s\" label=\"%s\">\n", n.Id, xmlEscape(n.Label))
+		fmt.Fprintf(out, "        <attvalues><attvalue for=\"faction\" value=\"%s\"/><attvalue for=\"type\" value=\"%s\"/></attvalues>\n",
+			FactionNames[n.Faction], CardTypeNames[n.Type])
+		fmt.Fprintf(out, "        <viz:color r=\"%d\" g=\"%d\" b=\"%d\"/>\n", r, gr, b)
+		fmt.Fprintf(out, "        <viz:shape value=\"%s\"/>\n", gexfShapes[n.Type])
+		fmt.Fprintln(out, `      </node>`)
+	}
+	fmt.Fprintln(out, `    </nodes>`)
+	fmt.Fprintln(out, `    <edges>`)
+	for i, e := range g.Edges {
+		effect, shape, color := "positive", "solid", "#2e8b57"
+		if !e.Positive {
+			effect, shape, color = "opponent", "dashed", "#b22222"
+		}
+		r, gr, b := hexColor(color)
+		fmt.Fprintf(out, "      <edge id=\"%d\" source=\"%s\" target=\"%s\" weight=\"%d\">\n", i, e.From, e.To, e.Weight)
+		fmt.Fprintf(out, "        <attvalues><attvalue for=\"effect\" value=\"%s\"/></attvalues>\n", effect)
+		fmt.Fprintf(out, "        <viz:color r=\"%d\" g=\"%d\" b=\"%d\"/><viz:shape value=\"%s\"/>\n", r, gr, b, shape)
+		fmt.Fprintln(out, `      </edge>`)
+	}
+	fmt.Fprintln(out, `    </edges>`)
+	fmt.Fprintln(out, `  </graph>`)
+	fmt.Fprintln(out, `</gexf>`)
+	return out.Flush()
+}
@@ -0,0 +1,21 @@
+package swcg
+
+import "testing"
+
+func TestObjectiveSetViewCountsCardsOncePerSet(t *testing.T) {
+	db := CreateDB()
+	_, cache := AnalyzeDB(db)
+	// both cards appear twice in their objective set
+	guardian, informant := testCard(db, "Guardian of Peace").Number, testCard(db, "Secret Informant").Number
+	graph := &SynergyGraph{Nodes: []int{guardian, informant},
+		Out: make(map[int][]SynergyEdge), In: make(map[int][]SynergyEdge)}
+	graph.addEdge(SynergyEdge{From: guardian, To: informant, Positive: true})
+
+	view := graph.ObjectiveSetView(cache)
+	if len(view.Edges) != 1 {
+		t.Fatalf("expected a single set edge, got %+v", view.Edges)
+	}
+	if e := view.Edges[0]; e.From != 5 || e.To != 18 || !e.Positive || e.Weight != 1 {
+		t.Errorf("expected an edge of weight 1 from set 5 to set 18, got %+v", e)
+	}
+}
@@ -0,0 +1,616 @@
+package swcg
+
+import "bytes"
+import "encoding/json"
+import "fmt"
+import "io"
+
+// JSON Schema ----------------------------------------------------------------
+//
+// The card DB is stored as {"version": 2, "cards": [...]}. Enumerations are
+// written with their names (FactionNames, CardTypeNames, ...) so that
+// reordering the Go constants doesn't break existing files. Polymorphic
+// values (card types, abilities, synergies) carry a "kind" discriminator.
+// Nil and empty lists are kept apart (null or omitted versus []), so a DB
+// survives a SaveDB / LoadDB round trip unchanged.
+//
+// When loading, a synergy can also be given as a synergy expression string
+// (see ParseSynergy), e.g. "synergies": ["all(type:Unit, not(trait:Vehicule))"].
+//
+// Version 2 added the ability scripts (trigger, triggerFilter, costs,
+// effects and oncePerTurn). Version 1 files still load, their abilities
+// being unscripted.
+
+const DBSchemaVersion = 2
+
+type jsonDB struct {
+	Version int        `json:"version"`
+	Cards   []jsonCard `json:"cards"`
+}
+
+type jsonCard struct {
+	Name          string           `json:"name"`
+	Faction       string           `json:"faction"`
+	Type          *jsonCardType    `json:"type"`
+	Cost          int              `json:"cost"`
+	Ressources    int              `json:"ressources"`
+	ForceIcons    int              `json:"forceIcons"`
+	CombatIcons   *jsonCombatIcons `json:"combatIcons"`
+	Abilities     []jsonAbility    `json:"abilities"`
+	Health        int              `json:"health"`
+	Quote         string           `json:"quote"`
+	ObjectiveSets []jsonSetEntry   `json:"objectiveSets"`
+	Set           string           `json:"set"`
+	Number        int              `json:"number"`
+}
+
+type jsonCombatIcons struct {
+	CombatDamage CombatIcon `json:"combatDamage"`
+	Tactics      CombatIcon `json:"tactics"`
+	BlastDamage  CombatIcon `json:"blastDamage"`
+}
+
+type jsonSetEntry struct {
+	SetId         int `json:"setId"`
+	CardSetNumber int `json:"cardSetNumber"`
+}
+
+// kind: Simple | Enhancement | Fate | Objective
+type jsonCardType struct {
+	Kind                   string         `json:"kind"`
+	Type                   string         `json:"type,omitempty"`
+	Synergies              *[]jsonSynergy `json:"synergies,omitempty"`
+	EdgeBattlePriority     int            `json:"edgeBattlePriority,omitempty"`
+	CancelsEdgeBattle      bool           `json:"cancelsEdgeBattle,omitempty"`
+	OnlyAvailableToFaction bool           `json:"onlyAvailableToFaction,omitempty"`
+}
+
+// kind: Ability | Trait | Keyword | ComplexKeyword | Protect
+type jsonAbility struct {
+	Kind          string         `json:"kind"`
+	Type          string         `json:"type,omitempty"`
+	Description   string         `json:"description,omitempty"`
+	Synergies     *[]jsonSynergy `json:"synergies,omitempty"`
+	Trigger       string         `json:"trigger,omitempty"`
+	TriggerFilter *jsonSynergy   `json:"triggerFilter,omitempty"`
+	Costs         *[]jsonEffect  `json:"costs,omitempty"`
+	Effects       *[]jsonEffect  `json:"effects,omitempty"`
+	OncePerTurn   bool           `json:"oncePerTurn,omitempty"`
+	Trait         string         `json:"trait,omitempty"`
+	Keyword       string         `json:"keyword,omitempty"`
+	Value         int            `json:"value,omitempty"`
+}
+
+type jsonEffect struct {
+	Type      string              `json:"type"`
+	Target    string              `json:"target"`
+	Amount    int                 `json:"amount,omitempty"`
+	Filter    *jsonSynergy        `json:"filter,omitempty"`
+	Condition string              `json:"condition,omitempty"`
+	Icons     *jsonCombatModifier `json:"icons,omitempty"`
+}
+
+type jsonCombatModifier struct {
+	CombatDamage   CombatIcon `json:"combatDamage"`
+	Tactics        CombatIcon `json:"tactics"`
+	BlastDamage    CombatIcon `json:"blastDamage"`
+	Health         int        `json:"health,omitempty"`
+	TargetedStrike bool       `json:"targetedStrike,omitempty"`
+}
+
+// kind: Type | Trait | PlayArea | Invert | Accumulate | Options
+type jsonSynergy struct {
+	Kind      string         `json:"kind"`
+	Positive  bool           `json:"positive,omitempty"`
+	Type      string         `json:"type,omitempty"`
+	Trait     string         `json:"trait,omitempty"`
+	Synergy   *jsonSynergy   `json:"synergy,omitempty"`
+	Synergies *[]jsonSynergy `json:"synergies,omitempty"`
+}
+
+type plainJSONSynergy jsonSynergy
+
+func (js *jsonSynergy) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '"' {
+		var expr string
+		if err := json.Unmarshal(trimmed, &expr); err != nil {
+			return err
+		}
+		syn, err := ParseSynergy(expr)
+		if err != nil {
+			return err
+		}
+		parsed, err := synergyToJSON(syn)
+		if err != nil {
+			return err
+		}
+		*js = *parsed
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode((*plainJSONSynergy)(js))
+}
+
+// Saving ---------------------------------------------------------------------
+
+func SaveDB(w io.Writer, db []Card) error {
+	out := jsonDB{Version: DBSchemaVersion, Cards: make([]jsonCard, len(db))}
+	for i := range db {
+		jc, err := cardToJSON(&db[i])
+		if err != nil {
+			return err
+		}
+		out.Cards[i] = jc
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&out)
+}
+
+func cardToJSON(c *Card) (jsonCard, error) {
+	jc := jsonCard{
+		Name:       c.Name,
+		Cost:       c.Cost,
+		Ressources: c.Ressources,
+		ForceIcons: c.ForceIcons,
+		Health:     c.Health,
+		Quote:      c.Quote,
+		Number:     c.Number,
+	}
+	var err error
+	if jc.Faction, err = enumName(FactionNames[:], int(c.Faction), "faction"); err != nil {
+		return jc, cardError(c, err)
+	}
+	if jc.Set, err = enumName(SetNames[:], int(c.Set), "card set"); err != nil {
+		return jc, cardError(c, err)
+	}
+	if jc.Type, err = cardTypeToJSON(c.Type); err != nil {
+		return jc, cardError(c, err)
+	}
+	if c.CardCombatIcons != nil {
+		jc.CombatIcons = &jsonCombatIcons{c.CardCombatIcons.CombatDamage, c.CardCombatIcons.Tactics, c.CardCombatIcons.BlastDamage}
+	}
+	if c.Abilities != nil {
+		jc.Abilities = make([]jsonAbility, len(c.Abilities))
+		for i, a := range c.Abilities {
+			if jc.Abilities[i], err = abilityToJSON(a); err != nil {
+				return jc, cardError(c, err)
+			}
+		}
+	}
+	if c.ObjectiveSets != nil {
+		jc.ObjectiveSets = make([]jsonSetEntry, len(c.ObjectiveSets))
+		for i, s := range c.ObjectiveSets {
+			jc.ObjectiveSets[i] = jsonSetEntry{s.SetId, s.CardSetNumber}
+		}
+	}
+	return jc, nil
+}
+
+func cardTypeToJSON(t CardTypeInterface) (*jsonCardType, error) {
+	var err error
+	jt := new(jsonCardType)
+	switch castedType := t.(type) {
+	case nil:
+		return nil, nil
+	case *EnhancementCardType:
+		jt.Kind = "Enhancement"
+		jt.Synergies, err = synergyListToJSON(castedType.Synergies)
+	case *FateCardType:
+		jt.Kind = "Fate"
+		jt.EdgeBattlePriority = castedType.EdgeBattlePriority
+		jt.CancelsEdgeBattle = castedType.CancelsEdgeBattle
+	case *ObjectiveCardType:
+		jt.Kind = "Objective"
+		jt.OnlyAvailableToFaction = castedType.OnlyAvailableToFaction
+	case *SimpleCardType:
+		jt.Kind = "Simple"
+		jt.Type, err = enumName(CardTypeNames[:], int(castedType.Type), "card type")
+	default:
+		err = fmt.Errorf("unsupported card type %T", t)
+	}
+	return jt, err
+}
+
+func abilityToJSON(a AbilityInterface) (jsonAbility, error) {
+	var err error
+	ja := jsonAbility{}
+	switch castedAbility := a.(type) {
+	case *CardAbility:
+		ja.Kind = "Ability"
+		ja.Description = castedAbility.Description
+		if ja.Type, err = enumName(AbilityNames[:], int(castedAbility.Type), "ability type"); err != nil {
+			return ja, err
+		}
+		if ja.Synergies, err = synergyListToJSON(castedAbility.Synergies); err != nil {
+			return ja, err
+		}
+		err = scriptToJSON(castedAbility, &ja)
+	case *CardTrait:
+		ja.Kind = "Trait"
+		ja.Trait, err = enumName(TraitNames[:], int(castedAbility.Trait), "trait")
+	case *ProtectKeywordType:
+		ja.Kind = "Protect"
+		ja.Trait, err = enumName(TraitNames[:], int(castedAbility.ProtectedTrait), "trait")
+	case *ComplexKeyword:
+		ja.Kind = "ComplexKeyword"
+		ja.Value = castedAbility.V
+		ja.Keyword, err = enumName(KeywordNames[:], int(castedAbility.K), "keyword")
+	case *SimpleKeyword:
+		ja.Kind = "Keyword"
+		ja.Keyword, err = enumName(KeywordNames[:], int(castedAbility.K), "keyword")
+	default:
+		err = fmt.Errorf("unsupported ability %T", a)
+	}
+	return ja, err
+}
+
+func scriptToJSON(a *CardAbility, ja *jsonAbility) error {
+	var err error
+	if a.Trigger != Trigger_None {
+		if ja.Trigger, err = enumName(TriggerNames[:], int(a.Trigger), "trigger"); err != nil {
+			return err
+		}
+	}
+	if a.TriggerFilter != nil {
+		if ja.TriggerFilter, err = synergyToJSON(a.TriggerFilter); err != nil {
+			return err
+		}
+	}
+	if ja.Costs, err = effectListToJSON(a.Costs); err != nil {
+		return err
+	}
+	ja.Effects, err = effectListToJSON(a.Effects)
+	ja.OncePerTurn = a.OncePerTurn
+	return err
+}
+
+func effectListToJSON(list []CardEffect) (*[]jsonEffect, error) {
+	if list == nil {
+		return nil, nil
+	}
+	out := make([]jsonEffect, len(list))
+	for i, e := range list {
+		je := &out[i]
+		var err error
+		if je.Type, err = enumName(EffectNames[:], int(e.Type), "effect type"); err != nil {
+			return nil, err
+		}
+		if je.Target, err = enumName(TargetNames[:], int(e.Target), "effect target"); err != nil {
+			return nil, err
+		}
+		je.Amount = e.Amount
+		if e.Filter != nil {
+			if je.Filter, err = synergyToJSON(e.Filter); err != nil {
+				return nil, err
+			}
+		}
+		if e.Condition != Condition_None {
+			if je.Condition, err = enumName(ConditionNames[:], int(e.Condition), "effect condition"); err != nil {
+				return nil, err
+			}
+		}
+		if e.Icons != (CombatModifier{}) {
+			je.Icons = &jsonCombatModifier{e.Icons.CombatDamage, e.Icons.Tactics, e.Icons.BlastDamage, e.Icons.Health, e.Icons.TargetedStrike}
+		}
+	}
+	return &out, nil
+}
+
+// Synergy lists are optional fields, a nil list is omitted while an empty
+// one is written as [].
+func synergyListToJSON(list []SynergyInterface) (*[]jsonSynergy, error) {
+	if list == nil {
+		return nil, nil
+	}
+	out := make([]jsonSynergy, len(list))
+	for i, s := range list {
+		js, err := synergyToJSON(s)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = *js
+	}
+	return &out, nil
+}
+
+func synergyToJSON(s SynergyInterface) (*jsonSynergy, error) {
+	var err error
+	js := new(jsonSynergy)
+	switch syn := s.(type) {
+	case *CardTypeSynergy:
+		js.Kind = "Type"
+		js.Positive = syn.IsPositiveEff
+		js.Type, err = enumName(CardTypeNames[:], int(syn.Type), "card type")
+	case *CardTraitSynergy:
+		js.Kind = "Trait"
+		js.Positive = syn.IsPositiveEff
+		js.Trait, err = enumName(TraitNames[:], int(syn.Trait), "trait")
+	case *PlayAreaSynergyType:
+		js.Kind = "PlayArea"
+	case *InvertedSynergyType:
+		js.Kind = "Invert"
+		js.Synergy, err = synergyToJSON(syn.synergy)
+	case *OptionalSynergyType:
+		js.Kind = "Options"
+		js.Synergies, err = synergyListToJSON(syn.synergies)
+	case *AccumulationSynergyType:
+		js.Kind = "Accumulate"
+		js.Synergies, err = synergyListToJSON(syn.synergies)
+	default:
+		err = fmt.Errorf("unsupported synergy %T", s)
+	}
+	return js, err
+}
+
+// Loading --------------------------------------------------------------------
+
+func LoadDB(r io.Reader) ([]Card, error) {
+	var in jsonDB
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&in); err != nil {
+		return nil, fmt.Errorf("can't decode card DB: %v", err)
+	}
+	if in.Version < 1 || in.Version > DBSchemaVersion {
+		return nil, fmt.Errorf("unsupported card DB version %d (expected 1 to %d)", in.Version, DBSchemaVersion)
+	}
+
+	db := make([]Card, len(in.Cards))
+	for i, jc := range in.Cards {
+		c, err := cardFromJSON(&jc)
+		if err != nil {
+			return nil, fmt.Errorf("card #%d (%s): %v", jc.Number, jc.Name, err)
+		}
+		db[i] = c
+	}
+	return db, nil
+}
+
+func cardFromJSON(jc *jsonCard) (Card, error) {
+	c := Card{
+		Name:       jc.Name,
+		Cost:       jc.Cost,
+		Ressources: jc.Ressources,
+		ForceIcons: jc.ForceIcons,
+		Health:     jc.Health,
+		Quote:      jc.Quote,
+		Number:     jc.Number,
+	}
+	faction, err := enumValue(FactionNames[:], jc.Faction, "faction")
+	if err != nil {
+		return c, err
+	}
+	c.Faction = CardFaction(faction)
+	set, err := enumValue(SetNames[:], jc.Set, "card set")
+	if err != nil {
+		return c, err
+	}
+	c.Set = CardSetType(set)
+	if c.Type, err = cardTypeFromJSON(jc.Type); err != nil {
+		return c, err
+	}
+	if jc.CombatIcons != nil {
+		c.CardCombatIcons = CombatIcons(jc.CombatIcons.CombatDamage, jc.CombatIcons.Tactics, jc.CombatIcons.BlastDamage)
+	}
+	if jc.Abilities != nil {
+		c.Abilities = make([]AbilityInterface, len(jc.Abilities))
+		for i := range jc.Abilities {
+			if c.Abilities[i], err = abilityFromJSON(&jc.Abilities[i]); err != nil {
+				return c, err
+			}
+		}
+	}
+	if jc.ObjectiveSets != nil {
+		c.ObjectiveSets = make([]ObjectiveSet, len(jc.ObjectiveSets))
+		for i, s := range jc.ObjectiveSets {
+			c.ObjectiveSets[i] = ObjectiveSet{SetId: s.SetId, CardSetNumber: s.CardSetNumber}
+		}
+	}
+	return c, nil
+}
+
+func cardTypeFromJSON(jt *jsonCardType) (CardTypeInterface, error) {
+	if jt == nil {
+		return nil, nil
+	}
+	switch jt.Kind {
+	case "Simple":
+		t, err := enumValue(CardTypeNames[:], jt.Type, "card type")
+		if err != nil {
+			return nil, err
+		}
+		return &SimpleCardType{Type: CardType(t)}, nil
+	case "Enhancement":
+		synergies, err := synergyListFromJSON(jt.Synergies)
+		if err != nil {
+			return nil, err
+		}
+		return Enhancement(synergies), nil
+	case "Fate":
+		if jt.CancelsEdgeBattle {
+			return FateCancel(jt.EdgeBattlePriority), nil
+		}
+		return Fate(jt.EdgeBattlePriority), nil
+	case "Objective":
+		return Objective(jt.OnlyAvailableToFaction), nil
+	}
+	return nil, fmt.Errorf("unknown card type kind %q", jt.Kind)
+}
+
+func abilityFromJSON(ja *jsonAbility) (AbilityInterface, error) {
+	switch ja.Kind {
+	case "Ability":
+		t, err := enumValue(AbilityNames[:], ja.Type, "ability type")
+		if err != nil {
+			return nil, err
+		}
+		if AbilityType(t) == AbilityType_Keyword || AbilityType(t) == AbilityType_Trait {
+			return nil, fmt.Errorf("%s is not a basic ability type", ja.Type)
+		}
+		synergies, err := synergyListFromJSON(ja.Synergies)
+		if err != nil {
+			return nil, err
+		}
+		ability := Ability(AbilityType(t), ja.Description, synergies)
+		return ability, scriptFromJSON(ja, ability)
+	case "Trait":
+		t, err := enumValue(TraitNames[:], ja.Trait, "trait")
+		if err != nil {
+			return nil, err
+		}
+		return Trait(CardTraitType(t)), nil
+	case "Protect":
+		t, err := enumValue(TraitNames[:], ja.Trait, "trait")
+		if err != nil {
+			return nil, err
+		}
+		return KeyProtect(CardTraitType(t)), nil
+	case "Keyword", "ComplexKeyword":
+		k, err := enumValue(KeywordNames[:], ja.Keyword, "keyword")
+		if err != nil {
+			return nil, err
+		}
+		if ja.Kind == "Keyword" {
+			return Key(CardKeywordType(k)), nil
+		}
+		return &ComplexKeyword{SimpleKeyword: *Key(CardKeywordType(k)), V: ja.Value}, nil
+	}
+	return nil, fmt.Errorf("unknown ability kind %q", ja.Kind)
+}
+
+func scriptFromJSON(ja *jsonAbility, a *CardAbility) error {
+	var err error
+	if ja.Trigger != "" {
+		t, err := enumValue(TriggerNames[:], ja.Trigger, "trigger")
+		if err != nil {
+			return err
+		}
+		a.Trigger = EffectTrigger(t)
+	}
+	if ja.TriggerFilter != nil {
+		if a.TriggerFilter, err = synergyFromJSON(ja.TriggerFilter); err != nil {
+			return err
+		}
+	}
+	if a.Costs, err = effectListFromJSON(ja.Costs); err != nil {
+		return err
+	}
+	a.Effects, err = effectListFromJSON(ja.Effects)
+	a.OncePerTurn = ja.OncePerTurn
+	return err
+}
+
+func effectListFromJSON(jsonList *[]jsonEffect) ([]CardEffect, error) {
+	if jsonList == nil {
+		return nil, nil
+	}
+	list := *jsonList
+	out := make([]CardEffect, len(list))
+	for i := range list {
+		je := &list[i]
+		t, err := enumValue(EffectNames[:], je.Type, "effect type")
+		if err != nil {
+			return nil, err
+		}
+		target, err := enumValue(TargetNames[:], je.Target, "effect target")
+		if err != nil {
+			return nil, err
+		}
+		e := Effect(EffectType(t), EffectTarget(target), je.Amount)
+		if je.Filter != nil {
+			if e.Filter, err = synergyFromJSON(je.Filter); err != nil {
+				return nil, err
+			}
+		}
+		if je.Condition != "" {
+			condition, err := enumValue(ConditionNames[:], je.Condition, "effect condition")
+			if err != nil {
+				return nil, err
+			}
+			e.Condition = EffectCondition(condition)
+		}
+		if je.Icons != nil {
+			e.Icons = CombatModifier{je.Icons.CombatDamage, je.Icons.Tactics, je.Icons.BlastDamage, je.Icons.Health, je.Icons.TargetedStrike}
+		}
+		out[i] = e
+	}
+	return out, nil
+}
+
+func synergyListFromJSON(jsonList *[]jsonSynergy) (SynergyList, error) {
+	if jsonList == nil {
+		return nil, nil
+	}
+	list := *jsonList
+	out := make(SynergyList, len(list))
+	for i := range list {
+		s, err := synergyFromJSON(&list[i])
+		if err != nil {
+			return nil, err
+		}
+		out[i] = s
+	}
+	return out, nil
+}
+
+func synergyFromJSON(js *jsonSynergy) (SynergyInterface, error) {
+	switch js.Kind {
+	case "Type":
+		t, err := enumValue(CardTypeNames[:], js.Type, "card type")
+		if err != nil {
+			return nil, err
+		}
+		return TypeSynergy(CardType(t), js.Positive), nil
+	case "Trait":
+		t, err := enumValue(TraitNames[:], js.Trait, "trait")
+		if err != nil {
+			return nil, err
+		}
+		return TraitSynergy(CardTraitType(t), js.Positive), nil
+	case "PlayArea":
+		return PlayAreaSynergy(), nil
+	case "Invert":
+		if js.Synergy == nil {
+			return nil, fmt.Errorf("inverted synergy without a synergy")
+		}
+		s, err := synergyFromJSON(js.Synergy)
+		if err != nil {
+			return nil, err
+		}
+		return InvertSynergy(s), nil
+	case "Accumulate", "Options":
+		list, err := synergyListFromJSON(js.Synergies)
+		if err != nil {
+			return nil, err
+		}
+		if js.Kind == "Accumulate" {
+			return AccumulateSynergies(list), nil
+		}
+		return SynergyOptions(list), nil
+	}
+	return nil, fmt.Errorf("unknown synergy kind %q", js.Kind)
+}
+
+// Enum Helpers ---------------------------------------------------------------
+
+func enumName(names []string, v int, what string) (string, error) {
+	if v < 0 || v >= len(names) {
+		return "", fmt.Errorf("invalid %s value %d", what, v)
+	}
+	return names[v], nil
+}
+
+func enumValue(names []string, name string, what string) (int, error) {
+	for i, n := range names {
+		if n == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown %s %q", what, name)
+}
+
+func cardError(c *Card, err error) error {
+	return fmt.Errorf("card #%d (%s): %v", c.Number, c.Name, err)
+}
@@ -0,0 +1,125 @@
+package swcg
+
+import "bytes"
+import "encoding/json"
+import "reflect"
+import "strings"
+import "testing"
+
+func TestSaveLoadDBRoundTrip(t *testing.T) {
+	db := CreateDB()
+	var saved bytes.Buffer
+	if err := SaveDB(&saved, db); err != nil {
+		t.Fatalf("SaveDB: %v", err)
+	}
+	loaded, err := LoadDB(bytes.NewReader(saved.Bytes()))
+	if err != nil {
+		t.Fatalf("LoadDB: %v", err)
+	}
+	if len(loaded) != len(db) {
+		t.Fatalf("loaded %d cards, saved %d", len(loaded), len(db))
+	}
+	for i := range db {
+		if !reflect.DeepEqual(&loaded[i], &db[i]) {
+			t.Errorf("card #%d (%s) changed by the round trip", db[i].Number, db[i].Name)
+		}
+	}
+
+	var resaved bytes.Buffer
+	if err := SaveDB(&resaved, loaded); err != nil {
+		t.Fatalf("SaveDB of the loaded DB: %v", err)
+	}
+	if !bytes.Equal(saved.Bytes(), resaved.Bytes()) {
+		t.Errorf("saving the loaded DB doesn't give the same file")
+	}
+}
+
+func TestLoadDBErrors(t *testing.T) {
+	tests := []struct {
+		json string
+		err  string
+	}{
+		{`{"version": 99, "cards": []}`, "unsupported card DB version 99"},
+		{`{"version": 0, "cards": []}`, "unsupported card DB version 0"},
+		{`{"version": 2, "cards": [], "extra": 1}`, "unknown field"},
+		{`{"version": 2, "cards": [{"name": "X", "faction": "Ewok", "set": "Core"}]}`, `unknown faction "Ewok"`},
+		{`{"version": 2, "cards": [{"name": "X", "faction": "Jedi", "set": "Core", "type": {"kind": "Simple", "type": "Unit"},
+			"abilities": [{"kind": "Ability", "type": "Action", "synergies": ["all(type:Unit"]}]}]}`, "synergy expression"},
+	}
+	for _, test := range tests {
+		_, err := LoadDB(strings.NewReader(test.json))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("LoadDB(%s) returned %v, expected an error containing %q", test.json, err, test.err)
+		}
+	}
+}
+
+// Saves the DB in the version 1 format: without the ability scripts.
+func saveDBVersion1(t *testing.T, db []Card) []byte {
+	var saved bytes.Buffer
+	if err := SaveDB(&saved, db); err != nil {
+		t.Fatalf("SaveDB: %v", err)
+	}
+	var file map[string]interface{}
+	if err := json.Unmarshal(saved.Bytes(), &file); err != nil {
+		t.Fatal(err)
+	}
+	file["version"] = 1
+	for _, card := range file["cards"].([]interface{}) {
+		abilities, _ := card.(map[string]interface{})["abilities"].([]interface{})
+		for _, ability := range abilities {
+			for _, field := range []string{"trigger", "triggerFilter", "costs", "effects", "oncePerTurn"} {
+				delete(ability.(map[string]interface{}), field)
+			}
+		}
+	}
+	v1, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v1
+}
+
+func TestLoadDBVersion1(t *testing.T) {
+	db := CreateDB()
+	loaded, err := LoadDB(bytes.NewReader(saveDBVersion1(t, db)))
+	if err != nil {
+		t.Fatalf("LoadDB of a version 1 file: %v", err)
+	}
+	for i := range db {
+		unscripted := db[i]
+		if db[i].Abilities != nil {
+			unscripted.Abilities = make(AbilityList, len(db[i].Abilities))
+		}
+		for j, ability := range db[i].Abilities {
+			unscripted.Abilities[j] = ability
+			if a, ok := ability.(*CardAbility); ok {
+				unscripted.Abilities[j] = Ability(a.Type, a.Description, a.Synergies)
+			}
+		}
+		if !reflect.DeepEqual(&loaded[i], &unscripted) {
+			t.Errorf("card #%d (%s) differs once loaded from a version 1 file", db[i].Number, db[i].Name)
+		}
+	}
+}
+
+func TestLoadDBCancelsEdgeBattle(t *testing.T) {
+	file := `{"version": 1, "cards": [{"name": "Twist of Fate", "faction": "LightNeutral", "set": "Core", "number": 1,
+		"type": {"kind": "Fate", "edgeBattlePriority": 1, "cancelsEdgeBattle": true}}]}`
+	loaded, err := LoadDB(strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("LoadDB: %v", err)
+	}
+	fate, ok := loaded[0].Type.(*FateCardType)
+	if !ok || !fate.CancelsEdgeBattle || fate.EdgeBattlePriority != 1 || !CardCancelsEdgeBattle(&loaded[0]) {
+		t.Errorf("cancelsEdgeBattle not loaded: %+v", loaded[0].Type)
+	}
+
+	var saved bytes.Buffer
+	if err := SaveDB(&saved, loaded); err != nil {
+		t.Fatalf("SaveDB: %v", err)
+	}
+	if !strings.Contains(saved.String(), `"cancelsEdgeBattle": true`) {
+		t.Errorf("cancelsEdgeBattle not saved:\n%s", saved.String())
+	}
+}
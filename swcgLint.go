@@ -0,0 +1,271 @@
+package swcg
+
+import "sort"
+import "strconv"
+
+// Lint Severity --------------------------------------------------------------
+
+type LintSeverity int
+const (
+	Severity_Info    LintSeverity = iota
+	Severity_Warning LintSeverity = iota
+	Severity_Error   LintSeverity = iota
+	Severity_MAX     LintSeverity = iota
+)
+var SeverityNames [Severity_MAX]string = [Severity_MAX]string {
+	"info",
+	"warning",
+	"error",
+}
+
+// Lint Findings --------------------------------------------------------------
+
+// A finding only refers to card numbers and set ids, never to source
+// locations, so it reads the same whether the DB came from CreateDB or
+// from a data file. CardNumber and SetId are 0 when not relevant.
+type LintFinding struct {
+	Rule       string
+	Severity   LintSeverity
+	CardNumber int
+	SetId      int
+	Message    string
+}
+func (f LintFinding) String() string {
+	out := SeverityNames[f.Severity]+" ["+f.Rule+"]"
+	if f.CardNumber != 0 {
+		out += " card #"+strconv.Itoa(f.CardNumber)
+	}
+	if f.SetId != 0 {
+		out += " set #"+strconv.Itoa(f.SetId)
+	}
+	return out+": "+f.Message
+}
+
+// Lint Rules -----------------------------------------------------------------
+
+type LintRule interface {
+	Name() string
+	Severity() LintSeverity
+	Check(db []Card) []LintFinding
+}
+type LintRuleList []LintRule
+
+type BaseLintRule struct {
+	RuleName     string
+	RuleSeverity LintSeverity
+}
+func (r BaseLintRule) Name() string           { return r.RuleName }
+func (r BaseLintRule) Severity() LintSeverity { return r.RuleSeverity }
+
+// Card Rule: checks every card on its own, an empty message means the card is fine
+
+type CardLintRuleType struct {
+	BaseLintRule
+	check func(c *Card) string
+}
+func CardLintRule(name string, severity LintSeverity, check func(c *Card) string) *CardLintRuleType {
+	return &CardLintRuleType{BaseLintRule: BaseLintRule{name, severity}, check: check}
+}
+func (r *CardLintRuleType) Check(db []Card) []LintFinding {
+	findings := make([]LintFinding, 0)
+	for i := range db {
+		if msg := r.check(&db[i]); msg != "" {
+			findings = append(findings, LintFinding{CardNumber: db[i].Number, Message: msg})
+		}
+	}
+	return findings
+}
+
+// DB Rule: free form check over the whole DB
+
+type DBLintRuleType struct {
+	BaseLintRule
+	check func(db []Card) []LintFinding
+}
+func DBLintRule(name string, severity LintSeverity, check func(db []Card) []LintFinding) *DBLintRuleType {
+	return &DBLintRuleType{BaseLintRule: BaseLintRule{name, severity}, check: check}
+}
+func (r *DBLintRuleType) Check(db []Card) []LintFinding {
+	return r.check(db)
+}
+
+// Default Rules --------------------------------------------------------------
+
+func DefaultLintRules() LintRuleList {
+	return LintRuleList{
+		DBLintRule("AnalyzeDB", Severity_Error, lintAnalyzeDB),
+		CardLintRule("CardTypeVariant", Severity_Error, lintCardTypeVariant),
+		CardLintRule("UnitCombatIcons", Severity_Warning, func(c *Card) string {
+			if c.Type != nil && c.Type.GetType() == CardType_Unit && c.CardCombatIcons == nil {
+				return c.Name+" is a unit without combat icons, use CombatIcons with zero values if it really has none"
+			}
+			return ""
+		}),
+		CardLintRule("UnitHealth", Severity_Error, func(c *Card) string {
+			if c.Type != nil && c.Type.GetType() == CardType_Unit && c.Health <= 0 {
+				return c.Name+" is a unit without health"
+			}
+			return ""
+		}),
+		CardLintRule("UndamageableHealth", Severity_Error, func(c *Card) string {
+			if c.Type == nil || c.Health == 0 {
+				return ""
+			}
+			if t := c.Type.GetType(); t != CardType_Unit && t != CardType_Objective {
+				return c.Name+" is of type "+CardTypeNames[t]+" but has "+strconv.Itoa(c.Health)+" health"
+			}
+			return ""
+		}),
+		CardLintRule("ObjectiveCost", Severity_Error, func(c *Card) string {
+			if c.Type != nil && c.Type.GetType() == CardType_Objective && c.Cost != 0 {
+				return "objective "+c.Name+" has a cost of "+strconv.Itoa(c.Cost)
+			}
+			return ""
+		}),
+		CardLintRule("ObjectiveHealth", Severity_Error, func(c *Card) string {
+			if c.Type != nil && c.Type.GetType() == CardType_Objective && c.Health <= 0 {
+				return "objective "+c.Name+" has no health"
+			}
+			return ""
+		}),
+		CardLintRule("CombatIconsOnNonUnit", Severity_Warning, func(c *Card) string {
+			if c.Type != nil && c.Type.GetType() != CardType_Unit && c.CardCombatIcons != nil {
+				return c.Name+" is of type "+CardTypeNames[c.Type.GetType()]+" but has combat icons"
+			}
+			return ""
+		}),
+		CardLintRule("MissingObjectiveSet", Severity_Warning, func(c *Card) string {
+			if len(c.ObjectiveSets) == 0 {
+				return c.Name+" isn't part of any objective set"
+			}
+			return ""
+		}),
+		CardLintRule("UnscriptedAbility", Severity_Warning, func(c *Card) string {
+			for _, ability := range c.Abilities {
+				if a, ok := ability.(*CardAbility); ok && !a.IsScripted() {
+					return c.Name+" has an ability without effects: "+a.Description
+				}
+			}
+			return ""
+		}),
+		DBLintRule("IncompleteObjectiveSet", Severity_Error, lintIncompleteSets),
+	}
+}
+
+// The panicking checks of AnalyzeDB, reported through the lint interface.
+func lintAnalyzeDB(db []Card) []LintFinding {
+	findings := make([]LintFinding, 0)
+	_, _, err := AnalyzeDBChecked(db)
+	if report, ok := err.(*ValidationReport); ok {
+		for _, e := range report.Errors {
+			findings = append(findings, LintFinding{
+				Rule:       "AnalyzeDB/"+ValidationRuleNames[e.Rule],
+				CardNumber: e.CardNumber,
+				SetId:      e.SetId,
+				Message:    e.Message,
+			})
+		}
+	}
+	return findings
+}
+
+// Enhancement, Fate and Objective cards carry extra data and must use their
+// dedicated constructors instead of a SimpleCardType.
+func lintCardTypeVariant(c *Card) string {
+	if c.Type == nil {
+		return ""
+	}
+	ok := true
+	switch c.Type.GetType() {
+	case CardType_Enhancement:
+		_, ok = c.Type.(*EnhancementCardType)
+	case CardType_Fate:
+		_, ok = c.Type.(*FateCardType)
+	case CardType_Objective:
+		_, ok = c.Type.(*ObjectiveCardType)
+	default:
+		if c.Type.GetType() < 0 || c.Type.GetType() >= CardType_MAX {
+			return c.Name+" has an unknown card type "+strconv.Itoa(int(c.Type.GetType()))
+		}
+	}
+	if !ok {
+		typeName := CardTypeNames[c.Type.GetType()]
+		return c.Name+" is a "+typeName+" card but isn't built with the "+typeName+" constructor"
+	}
+	return ""
+}
+
+func lintIncompleteSets(db []Card) []LintFinding {
+	filled := make(map[int]*[6]bool)
+	for _, c := range db {
+		for _, objSet := range c.ObjectiveSets {
+			if filled[objSet.SetId] == nil {
+				filled[objSet.SetId] = new([6]bool)
+			}
+			if objSet.CardSetNumber >= 1 && objSet.CardSetNumber <= 6 {
+				filled[objSet.SetId][objSet.CardSetNumber-1] = true
+			}
+		}
+	}
+
+	findings := make([]LintFinding, 0)
+	for setId, slots := range filled {
+		missing := ""
+		for i, ok := range slots {
+			if !ok {
+				if missing != "" {
+					missing += ", "
+				}
+				missing += strconv.Itoa(i+1)
+			}
+		}
+		if missing != "" {
+			findings = append(findings, LintFinding{SetId: setId, Message: "objective set is missing card(s) "+missing+" / 6"})
+		}
+	}
+	return findings
+}
+
+// Linting --------------------------------------------------------------------
+
+func Lint(db []Card) []LintFinding {
+	return LintWithRules(db, DefaultLintRules())
+}
+
+// Runs every rule over the DB. Findings are stamped with their rule's name
+// and severity (unless the rule already set a more specific name) and are
+// sorted by decreasing severity, then card number and set id.
+func LintWithRules(db []Card, rules LintRuleList) []LintFinding {
+	findings := make([]LintFinding, 0)
+	for _, rule := range rules {
+		for _, f := range rule.Check(db) {
+			if f.Rule == "" {
+				f.Rule = rule.Name()
+			}
+			f.Severity = rule.Severity()
+			findings = append(findings, f)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Severity != b.Severity {
+			return a.Severity > b.Severity
+		}
+		if a.CardNumber != b.CardNumber {
+			return a.CardNumber < b.CardNumber
+		}
+		return a.SetId < b.SetId
+	})
+	return findings
+}
+
+func FilterFindings(findings []LintFinding, minSeverity LintSeverity) []LintFinding {
+	filtered := make([]LintFinding, 0)
+	for _, f := range findings {
+		if f.Severity >= minSeverity {
+			filtered = append(filtered, f)
+		}
+	}
+	return filtered
+}
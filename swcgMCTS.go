@@ -0,0 +1,296 @@
+package swcg
+
+import "fmt"
+import "math"
+import "math/rand/v2"
+import "runtime"
+import "sort"
+import "sync"
+
+// Determinization ------------------------------------------------------------
+
+// Copy of the game where the information hidden from the observer is
+// resampled: the opponent's hand and face down edge stack are dealt again
+// from the cards it could hold, and the unknown deck orders are shuffled,
+// the random stream of the game included.
+func (g *Game) Determinize(observer int, rng *rand.Rand) *Game {
+	d := g.Clone()
+	opponent := d.Players[d.Opponent(observer)]
+	hidden := make([]int, 0, len(opponent.Hand)+len(opponent.EdgeStack)+len(opponent.CommandDeck))
+	hidden = append(hidden, opponent.Hand...)
+	for _, id := range opponent.EdgeStack {
+		if !d.Cards[id].Revealed {
+			hidden = append(hidden, id)
+		}
+	}
+	hidden = append(hidden, opponent.CommandDeck...)
+	rng.Shuffle(len(hidden), func(i, j int) { hidden[i], hidden[j] = hidden[j], hidden[i] })
+
+	deal := func(zone Zone, n int) []int {
+		ids := append([]int(nil), hidden[:n]...)
+		hidden = hidden[n:]
+		for _, id := range ids {
+			d.Cards[id].Zone = zone
+		}
+		return ids
+	}
+	opponent.Hand = deal(Zone_Hand, len(opponent.Hand))
+	edgeStack := make([]int, len(opponent.EdgeStack))
+	for i, id := range opponent.EdgeStack {
+		if d.Cards[id].Revealed {
+			edgeStack[i] = id
+		} else {
+			edgeStack[i] = deal(Zone_EdgeStack, 1)[0]
+		}
+	}
+	opponent.EdgeStack = edgeStack
+	opponent.CommandDeck = deal(Zone_CommandDeck, len(hidden))
+
+	for _, p := range d.Players {
+		rng.Shuffle(len(p.ObjectiveDeck), func(i, j int) { p.ObjectiveDeck[i], p.ObjectiveDeck[j] = p.ObjectiveDeck[j], p.ObjectiveDeck[i] })
+	}
+	own := d.Players[observer].CommandDeck
+	rng.Shuffle(len(own), func(i, j int) { own[i], own[j] = own[j], own[i] })
+	d.pcg = *rand.NewPCG(rng.Uint64(), rng.Uint64())
+	return d
+}
+
+// Monte Carlo Tree Search ----------------------------------------------------
+
+type MCTSConfig struct {
+	Iterations       int           // playouts per decision, split between the determinizations
+	Determinizations int           // defaults to Workers
+	Workers          int           // defaults to runtime.NumCPU()
+	Exploration      float64       // UCT exploration constant, defaults to 0.7
+	Rollout          PlayerFactory // defaults to NewGreedyPlayer
+	MaxRolloutMoves  int           // after which the game is evaluated, defaults to 300
+	Seed             uint64
+}
+
+func (config MCTSConfig) withDefaults() MCTSConfig {
+	if config.Iterations <= 0 {
+		config.Iterations = 1000
+	}
+	if config.Workers <= 0 {
+		config.Workers = runtime.NumCPU()
+	}
+	if config.Determinizations <= 0 {
+		config.Determinizations = config.Workers
+	}
+	if config.Exploration <= 0 {
+		config.Exploration = 0.7
+	}
+	if config.Rollout == nil {
+		config.Rollout = NewGreedyPlayer
+	}
+	if config.MaxRolloutMoves <= 0 {
+		config.MaxRolloutMoves = 300
+	}
+	return config
+}
+
+type MoveAdvice struct {
+	Move    Move
+	Visits  int
+	WinRate float64 // for the player to act, over the playouts starting with the move
+}
+
+type mctsNode struct {
+	move     Move
+	player   int // who played the move leading to the node
+	parent   *mctsNode
+	children []*mctsNode
+	untried  []Move
+	visits   int
+	wins     float64
+}
+
+func (n *mctsNode) selectChild(exploration float64) *mctsNode {
+	var best *mctsNode
+	bestValue := math.Inf(-1)
+	logVisits := math.Log(float64(n.visits))
+	for _, c := range n.children {
+		value := c.wins/float64(c.visits) + exploration*math.Sqrt(logVisits/float64(c.visits))
+		if value > bestValue {
+			best, bestValue = c, value
+		}
+	}
+	return best
+}
+
+// Evaluates the moves of the player to act. Every determinization gets its
+// own search tree, the trees are spread over the workers and their root
+// statistics summed up. Results are deterministic for a given seed and
+// number of determinizations. Interrupts and reactions aren't moves: both
+// players use them automatically when they can (see findResponse), so the
+// advice only weighs them through the playouts and never suggests holding
+// an event back for them.
+func AdviseMoves(g *Game, config MCTSConfig) []MoveAdvice {
+	config = config.withDefaults()
+	moves := g.LegalMoves()
+	if len(moves) <= 1 {
+		advice := make([]MoveAdvice, len(moves))
+		for i, m := range moves {
+			advice[i] = MoveAdvice{Move: m}
+		}
+		return advice
+	}
+
+	roots := make([]*mctsNode, config.Determinizations)
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < config.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for world := range next {
+				iterations := config.Iterations / config.Determinizations
+				if world < config.Iterations%config.Determinizations {
+					iterations++
+				}
+				rng := rand.New(rand.NewPCG(config.Seed, uint64(world)))
+				roots[world] = searchTree(g.Determinize(g.ToAct(), rng), iterations, config, rng)
+			}
+		}()
+	}
+	for world := range roots {
+		next <- world
+	}
+	close(next)
+	wg.Wait()
+
+	stats := make(map[Move]*MoveAdvice)
+	for _, m := range moves {
+		stats[m] = &MoveAdvice{Move: m}
+	}
+	wins := make(map[Move]float64)
+	for _, root := range roots {
+		for _, c := range root.children {
+			if advice, ok := stats[c.move]; ok {
+				advice.Visits += c.visits
+				wins[c.move] += c.wins
+			}
+		}
+	}
+	advice := make([]MoveAdvice, 0, len(moves))
+	for _, m := range moves {
+		a := stats[m]
+		if a.Visits > 0 {
+			a.WinRate = wins[m] / float64(a.Visits)
+		}
+		advice = append(advice, *a)
+	}
+	sort.SliceStable(advice, func(i, j int) bool { return advice[i].Visits > advice[j].Visits })
+	return advice
+}
+
+func searchTree(root *Game, iterations int, config MCTSConfig, rng *rand.Rand) *mctsNode {
+	tree := &mctsNode{player: NoPlayer, untried: root.LegalMoves()}
+search:
+	for i := 0; i < iterations; i++ {
+		g := root.Clone()
+		node := tree
+
+		for len(node.untried) == 0 && len(node.children) > 0 {
+			node = node.selectChild(config.Exploration)
+			if err := g.Apply(node.move); err != nil {
+				continue search // abandons the iteration
+			}
+		}
+		expanded := false
+		if len(node.untried) > 0 {
+			k := rng.IntN(len(node.untried))
+			m := node.untried[k]
+			node.untried = append(node.untried[:k], node.untried[k+1:]...)
+			player := g.ToAct()
+			if err := g.Apply(m); err != nil {
+				continue
+			}
+			child := &mctsNode{move: m, player: player, parent: node, untried: g.LegalMoves()}
+			node.children = append(node.children, child)
+			node, expanded = child, true
+		}
+
+		rewards, err := rollout(g, config, rng.Uint64())
+		if err != nil {
+			if expanded {
+				// unvisited children can't be selected
+				node.parent.children = node.parent.children[:len(node.parent.children)-1]
+			}
+			continue
+		}
+		for ; node != nil; node = node.parent {
+			node.visits++
+			if node.player != NoPlayer {
+				node.wins += rewards[node.player]
+			}
+		}
+	}
+	return tree
+}
+
+// Plays the game to its end with the rollout policy and returns the reward
+// of each player: 1 for a win, or the heuristic evaluation of the game when
+// the rollout is cut short. Fails like playGame when no move is legal or a
+// chosen move can't be applied.
+func rollout(g *Game, config MCTSConfig, seed uint64) ([2]float64, error) {
+	players := [2]Player{config.Rollout(seed), config.Rollout(seed ^ 1)}
+	for moves := 0; !g.IsOver() && moves < config.MaxRolloutMoves; moves++ {
+		legal := g.LegalMoves()
+		if len(legal) == 0 {
+			return [2]float64{}, fmt.Errorf("no legal move during %s/%s", PhaseNames[g.Phase], StepNames[g.Step])
+		}
+		if err := g.Apply(players[g.ToAct()].ChooseMove(g, legal)); err != nil {
+			return [2]float64{}, err
+		}
+	}
+	if g.IsOver() {
+		rewards := [2]float64{}
+		rewards[g.Winner] = 1
+		return rewards, nil
+	}
+	dark := EvaluateGame(g)
+	return [2]float64{dark, 1-dark}, nil
+}
+
+// Estimated odds of the dark side player winning the game in progress, from
+// the progress of both victory conditions.
+func EvaluateGame(g *Game) float64 {
+	if g.IsOver() {
+		if g.Winner == Player_Dark {
+			return 1
+		}
+		return 0
+	}
+	darkProgress := math.Max(float64(g.DeathStarDial)/DeathStarDialToWin, float64(len(g.Players[Player_Light].DestroyedObjectives))/ObjectivesToWin)
+	lightProgress := float64(len(g.Players[Player_Dark].DestroyedObjectives)) / ObjectivesToWin
+	return 0.5 + (darkProgress-lightProgress)/2
+}
+
+// MCTS Player ----------------------------------------------------------------
+
+type MCTSPlayer struct {
+	Config    MCTSConfig
+	decisions uint64
+}
+
+// Factory of MCTS players, each seeded from its game.
+func MCTSPlayerFactory(config MCTSConfig) PlayerFactory {
+	return func(seed uint64) Player {
+		config.Seed = seed
+		return &MCTSPlayer{Config: config}
+	}
+}
+
+func (p *MCTSPlayer) Name() string { return "MCTS" }
+
+func (p *MCTSPlayer) ChooseMove(g *Game, moves []Move) Move {
+	if len(moves) == 1 {
+		return moves[0]
+	}
+	config := p.Config
+	config.Seed = p.Config.Seed ^ p.decisions*0x9e3779b97f4a7c15
+	p.decisions++
+	advice := AdviseMoves(g, config)
+	return advice[0].Move
+}
@@ -0,0 +1,30 @@
+package swcg
+
+import "math/rand/v2"
+import "testing"
+
+func TestSearchWithoutLegalMoves(t *testing.T) {
+	dark, light := testDecks(t)
+	g := NewGame(dark, light, 1)
+	g.Step = Step_None // no player to act
+	config := MCTSConfig{Iterations: 5, Rollout: NewRandomPlayer}.withDefaults()
+	if _, err := rollout(g.Clone(), config, 1); err == nil {
+		t.Errorf("a rollout without legal moves should fail")
+	}
+	if tree := searchTree(g, 5, config, rand.New(rand.NewPCG(1, 0))); tree.visits != 0 || len(tree.children) != 0 {
+		t.Errorf("failed iterations should be abandoned, got %d visits", tree.visits)
+	}
+}
+
+func TestRolloutPlaysToTheEnd(t *testing.T) {
+	dark, light := testDecks(t)
+	config := MCTSConfig{Rollout: NewRandomPlayer}.withDefaults()
+	config.MaxRolloutMoves = 100000
+	rewards, err := rollout(NewGame(dark, light, 2), config, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rewards[0]+rewards[1] != 1 || rewards[0]*rewards[1] != 0 {
+		t.Errorf("a finished rollout should reward the winner only, got %v", rewards)
+	}
+}
@@ -0,0 +1,249 @@
+package swcg
+
+import "encoding/csv"
+import "encoding/json"
+import "fmt"
+import "io"
+import "math"
+import "runtime"
+import "strconv"
+import "strings"
+import "sync"
+
+// Deck Definitions -----------------------------------------------------------
+
+type DeckDefinition struct {
+	Name    string
+	Faction CardFaction // Faction_MAX to infer it from the objective sets
+	SetIds  []int
+}
+
+// Parses "[name=][faction:]id,id,...", e.g. "Yoda=Jedi:1,1,2,2,3,3,4,5,6,18".
+// The name defaults to the definition itself.
+func ParseDeckDefinition(s string) (DeckDefinition, error) {
+	def := DeckDefinition{Name: s, Faction: Faction_MAX}
+	rest := s
+	if name, sets, ok := strings.Cut(rest, "="); ok {
+		def.Name, rest = strings.TrimSpace(name), sets
+	}
+	if faction, sets, ok := strings.Cut(rest, ":"); ok {
+		f, err := enumValue(FactionNames[:], strings.TrimSpace(faction), "faction")
+		if err != nil {
+			return def, err
+		}
+		def.Faction, rest = CardFaction(f), sets
+	}
+	for _, field := range strings.Split(rest, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return def, fmt.Errorf("invalid objective set id %q in deck %s", field, def.Name)
+		}
+		def.SetIds = append(def.SetIds, id)
+	}
+	return def, nil
+}
+
+// Creates the deck, inferring its faction from its objectives when not
+// given: the most represented non neutral faction.
+func (def DeckDefinition) Build(cache *DataCache) (*Deck, error) {
+	faction := def.Faction
+	if faction == Faction_MAX {
+		counts := make(map[CardFaction]int)
+		for _, id := range def.SetIds {
+			set, ok := (*cache.SetMap)[id]
+			if !ok {
+				return nil, fmt.Errorf("unknown objective set %d in deck %s", id, def.Name)
+			}
+			if set[0] != nil && !set[0].Faction.IsNeutral() {
+				counts[set[0].Faction]++
+			}
+		}
+		for f, n := range counts {
+			if faction == Faction_MAX || n > counts[faction] || n == counts[faction] && f < faction {
+				faction = f
+			}
+		}
+		if faction == Faction_MAX {
+			return nil, fmt.Errorf("can't infer the faction of deck %s", def.Name)
+		}
+	}
+	return CreateDeck(cache, faction, def.SetIds...)
+}
+
+// Confidence Intervals -------------------------------------------------------
+
+// Wilson score interval of a win rate, z being the normal quantile (1.96
+// for 95%).
+func WilsonInterval(wins, games int, z float64) (float64, float64) {
+	if games == 0 {
+		return 0, 1
+	}
+	n := float64(games)
+	p := float64(wins) / n
+	center := (p + z*z/(2*n)) / (1 + z*z/n)
+	margin := z / (1 + z*z/n) * math.Sqrt(p*(1-p)/n+z*z/(4*n*n))
+	return math.Max(0, center-margin), math.Min(1, center+margin)
+}
+
+// Matchups -------------------------------------------------------------------
+
+type MatchupConfig struct {
+	Games   int           // per pairing, both decks playing each side half of the games
+	AI      PlayerFactory // plays both decks
+	Seed    uint64
+	Workers int           // defaults to runtime.NumCPU()
+	Z       float64       // confidence interval quantile, defaults to 1.96
+}
+
+type MatchupCell struct {
+	Wins    int     `json:"wins"`
+	Games   int     `json:"games"`
+	WinRate float64 `json:"winRate"`
+	Low     float64 `json:"low"`
+	High    float64 `json:"high"`
+}
+
+// Cells[i][j] is the result of deck i against deck j.
+type MatchupMatrix struct {
+	Decks   []string        `json:"decks"`
+	Cells   [][]MatchupCell `json:"cells"`
+	Overall []MatchupCell   `json:"overall"`
+}
+
+type matchupGame struct {
+	a, b int
+	game int
+}
+
+// Plays every pairing of decks. Game seeds only depend on the pairing and
+// the game number, so the matrix doesn't depend on the number of workers.
+func RunMatchups(names []string, decks []*Deck, config MatchupConfig) (*MatchupMatrix, error) {
+	workers := config.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	z := config.Z
+	if z <= 0 {
+		z = 1.96
+	}
+	ai := config.AI
+	if ai == nil {
+		ai = NewGreedyPlayer
+	}
+
+	n := len(decks)
+	wins := make([][]int, n)
+	games := make([][]int, n)
+	for i := range wins {
+		wins[i], games[i] = make([]int, n), make([]int, n)
+	}
+
+	jobs := make(chan matchupGame)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	var firstErr error
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				pairing := uint64(job.a*n + job.b)
+				winner, _, err := playMatchGame([2]*Deck{decks[job.a], decks[job.b]}, [2]PlayerFactory{ai, ai}, job.game, config.Seed+pairing*uint64(config.Games))
+				mutex.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = fmt.Errorf("%s vs %s: %v", names[job.a], names[job.b], err)
+				}
+				if err == nil {
+					games[job.a][job.b]++
+					games[job.b][job.a]++
+					if winner == 0 {
+						wins[job.a][job.b]++
+					} else {
+						wins[job.b][job.a]++
+					}
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	for a := 0; a < n; a++ {
+		for b := a + 1; b < n; b++ {
+			for game := 0; game < config.Games; game++ {
+				jobs <- matchupGame{a, b, game}
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	matrix := &MatchupMatrix{Decks: names, Cells: make([][]MatchupCell, n), Overall: make([]MatchupCell, n)}
+	cell := func(w, g int) MatchupCell {
+		c := MatchupCell{Wins: w, Games: g}
+		if g > 0 {
+			c.WinRate = float64(w) / float64(g)
+		}
+		c.Low, c.High = WilsonInterval(w, g, z)
+		return c
+	}
+	for i := 0; i < n; i++ {
+		matrix.Cells[i] = make([]MatchupCell, n)
+		totalWins, totalGames := 0, 0
+		for j := 0; j < n; j++ {
+			matrix.Cells[i][j] = cell(wins[i][j], games[i][j])
+			totalWins += wins[i][j]
+			totalGames += games[i][j]
+		}
+		matrix.Overall[i] = cell(totalWins, totalGames)
+	}
+	return matrix, nil
+}
+
+func formatMatchupCell(c MatchupCell) string {
+	if c.Games == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%% [%.1f-%.1f]", 100*c.WinRate, 100*c.Low, 100*c.High)
+}
+
+// Win rates of the row decks against the column decks, with their
+// confidence intervals.
+func (m *MatchupMatrix) DataCollection() *DataCollection {
+	data := CreateDataCollection(append(append([]string{"Deck"}, m.Decks...), "Overall")...)
+	for i, name := range m.Decks {
+		row := []interface{}{name}
+		for _, c := range m.Cells[i] {
+			row = append(row, formatMatchupCell(c))
+		}
+		data.AddRow(append(row, formatMatchupCell(m.Overall[i]))...)
+	}
+	return data
+}
+
+func (m *MatchupMatrix) Print() string {
+	return m.DataCollection().Print()
+}
+
+// One row per pairing: deck, opponent, wins, games, win rate and interval.
+func (m *MatchupMatrix) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"deck", "opponent", "wins", "games", "win_rate", "low", "high"})
+	format := func(f float64) string { return strconv.FormatFloat(f, 'f', 4, 64) }
+	for i, name := range m.Decks {
+		for j, opponent := range m.Decks {
+			if c := m.Cells[i][j]; c.Games > 0 {
+				writer.Write([]string{name, opponent, strconv.Itoa(c.Wins), strconv.Itoa(c.Games), format(c.WinRate), format(c.Low), format(c.High)})
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (m *MatchupMatrix) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(m)
+}
@@ -0,0 +1,365 @@
+package swcg
+
+import "fmt"
+import "math"
+import "math/rand/v2"
+import "runtime"
+import "sort"
+import "strconv"
+import "strings"
+import "sync"
+import "time"
+
+// Deck Objectives ------------------------------------------------------------
+
+// Value of a deck to maximize.
+type DeckObjective func(d *Deck) float64
+
+// ScoreDeck total against the opponent deck (nil for positive synergies only).
+func SynergyObjective(graph *SynergyGraph, opponent *Deck) DeckObjective {
+	return func(d *Deck) float64 { return float64(ScoreDeck(graph, d, opponent).Total()) }
+}
+
+// Fraction of the command deck cards costing 0, 1, 2, 3 and 4 or more a
+// smooth deck aims for.
+var ResourceCurveTarget = [5]float64{0.2, 0.3, 0.25, 0.15, 0.1}
+
+// Similarity of the cost distribution of the command deck with
+// ResourceCurveTarget, from 0 to 1. Fates cost nothing to play from the edge
+// stack and are left out.
+func ResourceCurveObjective() DeckObjective {
+	return func(d *Deck) float64 {
+		var counts [len(ResourceCurveTarget)]float64
+		total := 0.0
+		for _, c := range d.CommandDeck() {
+			if c.Type.GetType() != CardType_Fate {
+				counts[min(c.Cost, len(counts)-1)]++
+				total++
+			}
+		}
+		if total == 0 {
+			return 0
+		}
+		distance := 0.0
+		for i, n := range counts {
+			distance += math.Abs(n/total - ResourceCurveTarget[i])
+		}
+		return 1 - distance/2
+	}
+}
+
+// Average number of Force icons of the command deck cards.
+func ForceIconObjective() DeckObjective {
+	return func(d *Deck) float64 {
+		cards := d.CommandDeck()
+		icons := 0
+		for _, c := range cards {
+			icons += c.ForceIcons
+		}
+		return float64(icons) / float64(max(1, len(cards)))
+	}
+}
+
+// Win rate of the deck over a match of the given number of games against
+// each opponent, played by the AI. Expensive, keep the games count low.
+func WinRateObjective(opponents []*Deck, games int, ai PlayerFactory, seed uint64) DeckObjective {
+	return func(d *Deck) float64 {
+		wins, played := 0, 0
+		for _, opponent := range opponents {
+			result, err := PlayMatch([2]*Deck{d, opponent}, [2]PlayerFactory{ai, ai}, games, seed)
+			if err != nil {
+				return 0
+			}
+			wins += result.Wins[0]
+			played += result.Games
+		}
+		return float64(wins) / float64(max(1, played))
+	}
+}
+
+type WeightedObjective struct {
+	Objective DeckObjective
+	Weight    float64
+}
+
+// Weighted sum of several objectives.
+func CombineObjectives(terms ...WeightedObjective) DeckObjective {
+	return func(d *Deck) float64 {
+		value := 0.0
+		for _, t := range terms {
+			value += t.Weight * t.Objective(d)
+		}
+		return value
+	}
+}
+
+// Optimizer ------------------------------------------------------------------
+
+// Number of copies of a set the deck must hold, e.g. {SetId: 2, Min: 2,
+// Max: 2} to include set 2 twice or {SetId: 7, Max: 0} to exclude set 7.
+type SetConstraint struct {
+	SetId int
+	Min   int
+	Max   int
+}
+
+func IncludeSet(setId, copies int) SetConstraint { return SetConstraint{SetId: setId, Min: copies, Max: copies} }
+func ExcludeSet(setId int) SetConstraint         { return SetConstraint{SetId: setId, Min: 0, Max: 0} }
+
+type OptimizerConfig struct {
+	Faction     CardFaction
+	Objective   DeckObjective   // defaults to SynergyObjective without opponent
+	Constraints []SetConstraint
+	Runs        int             // independent annealing runs, defaults to Workers
+	Workers     int             // defaults to runtime.NumCPU()
+	Iterations  int             // per run, defaults to 2000 without Budget, unlimited with it
+	Budget      time.Duration   // wall clock limit of the whole search, 0 for none
+	Temperature float64         // initial relative temperature, defaults to 0.1
+	Seed        uint64
+}
+
+type OptimizedDeck struct {
+	Deck        *Deck
+	Score       float64
+	Evaluations int // distinct decks scored by each run, summed over the runs
+}
+
+type setCounts map[int]int
+
+func (counts setCounts) key(ids []int) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.Itoa(counts[id]))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (counts setCounts) setIds(ids []int) []int {
+	setIds := make([]int, 0, DeckObjectiveSetCount)
+	for _, id := range ids {
+		for i := 0; i < counts[id]; i++ {
+			setIds = append(setIds, id)
+		}
+	}
+	return setIds
+}
+
+type optimizer struct {
+	cache      *DataCache
+	config     OptimizerConfig
+	candidates []int       // set ids the faction may use, sorted
+	low, high  map[int]int // copies bounds by set id
+	deadline   time.Time
+}
+
+// Searches the legal decks of the faction for the one maximizing the
+// objective with simulated annealing. A move swaps one copy of a set for a
+// copy of another while respecting the constraints. Runs are spread over
+// the workers and the best deck of all the runs is returned. Without a time
+// budget the result only depends on the seed and the number of runs.
+func OptimizeDeck(cache *DataCache, config OptimizerConfig) (*OptimizedDeck, error) {
+	if config.Workers <= 0 {
+		config.Workers = runtime.NumCPU()
+	}
+	if config.Runs <= 0 {
+		config.Runs = config.Workers
+	}
+	if config.Iterations <= 0 && config.Budget <= 0 {
+		config.Iterations = 2000
+	}
+	if config.Temperature <= 0 {
+		config.Temperature = 0.1
+	}
+	if config.Objective == nil {
+		config.Objective = SynergyObjective(BuildSynergyGraph(cache), nil)
+	}
+	o := &optimizer{cache: cache, config: config, low: make(map[int]int), high: make(map[int]int)}
+	if config.Budget > 0 {
+		o.deadline = time.Now().Add(config.Budget)
+	}
+	if err := o.findCandidates(); err != nil {
+		return nil, err
+	}
+
+	results := make([]*OptimizedDeck, config.Runs)
+	var wg sync.WaitGroup
+	runs := make(chan int)
+	for w := 0; w < config.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for run := range runs {
+				results[run] = o.anneal(rand.New(rand.NewPCG(config.Seed, uint64(run))))
+			}
+		}()
+	}
+	for run := 0; run < config.Runs; run++ {
+		runs <- run
+	}
+	close(runs)
+	wg.Wait()
+
+	best := results[0]
+	for _, r := range results[1:] {
+		if r.Score > best.Score {
+			best.Deck, best.Score = r.Deck, r.Score
+		}
+		best.Evaluations += r.Evaluations
+	}
+	return best, nil
+}
+
+// Keeps the sets a deck of the faction may hold and checks the constraints
+// can be met.
+func (o *optimizer) findCandidates() error {
+	ids := make([]int, 0, len(*o.cache.SetMap))
+	for id := range *o.cache.SetMap {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		d, err := CreateDeck(o.cache, o.config.Faction, id)
+		if err != nil {
+			return err
+		}
+		allowed := true
+		if report, ok := d.Validate().(*ValidationReport); ok {
+			for _, e := range report.Errors {
+				switch e.Rule {
+				case Rule_DeckSetCount:
+				case Rule_DeckNeutralFaction, Rule_DeckAffiliation:
+					return e
+				default:
+					allowed = false
+				}
+			}
+		}
+		if allowed {
+			o.candidates = append(o.candidates, id)
+			o.high[id] = DeckMaxSetCopies
+		}
+	}
+
+	for _, c := range o.config.Constraints {
+		if _, ok := o.high[c.SetId]; !ok {
+			if c.Min > 0 {
+				return fmt.Errorf("objective set #%d can't be part of a %s deck", c.SetId, FactionNames[o.config.Faction])
+			}
+			continue
+		}
+		o.low[c.SetId] = max(o.low[c.SetId], c.Min)
+		o.high[c.SetId] = min(o.high[c.SetId], c.Max)
+		if o.low[c.SetId] > o.high[c.SetId] {
+			return fmt.Errorf("conflicting constraints on objective set #%d", c.SetId)
+		}
+	}
+	lowTotal, highTotal := 0, 0
+	for _, id := range o.candidates {
+		lowTotal += o.low[id]
+		highTotal += o.high[id]
+	}
+	if lowTotal > DeckObjectiveSetCount || highTotal < DeckObjectiveSetCount {
+		return fmt.Errorf("no %s deck of %d objective sets meets the constraints", FactionNames[o.config.Faction], DeckObjectiveSetCount)
+	}
+	return nil
+}
+
+func (o *optimizer) deck(counts setCounts) *Deck {
+	d, _ := CreateDeck(o.cache, o.config.Faction, counts.setIds(o.candidates)...)
+	return d
+}
+
+func (o *optimizer) expired() bool {
+	return !o.deadline.IsZero() && time.Now().After(o.deadline)
+}
+
+// Random legal deck: the required copies, completed at random.
+func (o *optimizer) randomCounts(rng *rand.Rand) setCounts {
+	counts := make(setCounts)
+	total := 0
+	for _, id := range o.candidates {
+		counts[id] = o.low[id]
+		total += counts[id]
+	}
+	for total < DeckObjectiveSetCount {
+		id := o.candidates[rng.IntN(len(o.candidates))]
+		if counts[id] < o.high[id] {
+			counts[id]++
+			total++
+		}
+	}
+	return counts
+}
+
+// Swaps a copy of a set for a copy of another one, or returns nil when no
+// swap is possible.
+func (o *optimizer) neighbour(counts setCounts, rng *rand.Rand) setCounts {
+	removable, addable := make([]int, 0), make([]int, 0)
+	for _, id := range o.candidates {
+		if counts[id] > o.low[id] {
+			removable = append(removable, id)
+		}
+		if counts[id] < o.high[id] {
+			addable = append(addable, id)
+		}
+	}
+	if len(removable) == 0 || len(addable) == 0 {
+		return nil
+	}
+	out, in := removable[rng.IntN(len(removable))], addable[rng.IntN(len(addable))]
+	if out == in {
+		return nil
+	}
+	next := make(setCounts, len(counts))
+	for id, n := range counts {
+		next[id] = n
+	}
+	next[out]--
+	next[in]++
+	return next
+}
+
+func (o *optimizer) anneal(rng *rand.Rand) *OptimizedDeck {
+	scores := make(map[string]float64)
+	evaluate := func(counts setCounts) float64 {
+		key := counts.key(o.candidates)
+		if score, ok := scores[key]; ok {
+			return score
+		}
+		score := o.config.Objective(o.deck(counts))
+		scores[key] = score
+		return score
+	}
+
+	current := o.randomCounts(rng)
+	currentScore := evaluate(current)
+	best, bestScore := current, currentScore
+	scale := math.Max(math.Abs(currentScore), 1e-9)
+	start := time.Now()
+	for i := 0; o.config.Iterations <= 0 || i < o.config.Iterations; i++ {
+		if o.expired() {
+			break
+		}
+		progress := 0.0
+		if o.config.Iterations > 0 {
+			progress = float64(i) / float64(o.config.Iterations)
+		} else {
+			progress = float64(time.Since(start)) / float64(o.deadline.Sub(start))
+		}
+		temperature := o.config.Temperature * (1 - progress)
+
+		next := o.neighbour(current, rng)
+		if next == nil {
+			continue
+		}
+		nextScore := evaluate(next)
+		delta := (nextScore - currentScore) / scale
+		if delta >= 0 || temperature > 0 && rng.Float64() < math.Exp(delta/temperature) {
+			current, currentScore = next, nextScore
+			if currentScore > bestScore {
+				best, bestScore = current, currentScore
+			}
+		}
+	}
+	return &OptimizedDeck{Deck: o.deck(best), Score: bestScore, Evaluations: len(scores)}
+}
@@ -0,0 +1,35 @@
+package swcg
+
+import "slices"
+import "testing"
+
+func TestOptimizeDeckIsDeterministic(t *testing.T) {
+	_, cache := AnalyzeDB(CreateDB())
+	config := OptimizerConfig{Faction: Faction_Jedi, Constraints: []SetConstraint{IncludeSet(3, 2)},
+		Runs: 2, Workers: 1, Iterations: 200, Seed: 7}
+	first, err := OptimizeDeck(cache, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copies := 0
+	for _, id := range first.Deck.SetIds {
+		if id == 3 {
+			copies++
+		}
+	}
+	if copies != 2 || len(first.Deck.SetIds) != DeckObjectiveSetCount {
+		t.Errorf("expected %d sets with 2 copies of set #3, got %v", DeckObjectiveSetCount, first.Deck.SetIds)
+	}
+	if err := first.Deck.Validate(); err != nil {
+		t.Errorf("optimized deck %v is not legal: %v", first.Deck.SetIds, err)
+	}
+
+	second, err := OptimizeDeck(cache, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(first.Deck.SetIds, second.Deck.SetIds) || first.Score != second.Score || first.Evaluations != second.Evaluations {
+		t.Errorf("same seed gave %v (%v, %d evaluations) then %v (%v, %d evaluations)", first.Deck.SetIds, first.Score,
+			first.Evaluations, second.Deck.SetIds, second.Score, second.Evaluations)
+	}
+}
@@ -0,0 +1,158 @@
+package swcg
+
+import "math/big"
+import "strings"
+
+// Card Predicates ------------------------------------------------------------
+
+type CardPredicate func(*Card) bool
+
+func NamePredicate(name string) CardPredicate {
+	return func(c *Card) bool { return strings.EqualFold(c.Name, name) }
+}
+func TypePredicate(t CardType) CardPredicate {
+	return func(c *Card) bool { return c.Type.GetType() == t }
+}
+func TraitPredicate(t CardTraitType) CardPredicate {
+	return TraitSynergy(t, true).IsSynergizingWith
+}
+func KeywordPredicate(k CardKeywordType) CardPredicate {
+	return func(c *Card) bool {
+		for _, ability := range c.Abilities {
+			if keyword, ok := ability.(KeywordInterface); ok && keyword.GetKeyword() == k {
+				return true
+			}
+		}
+		return false
+	}
+}
+// Cards the synergy applies to, e.g. MustParseSynergy("all(type:Unit, trait:ForceUser)").
+func SynergyPredicate(s SynergyInterface) CardPredicate {
+	return s.IsSynergizingWith
+}
+func AnyPredicate(predicates ...CardPredicate) CardPredicate {
+	return func(c *Card) bool {
+		for _, p := range predicates {
+			if p(c) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Hypergeometric Distribution ------------------------------------------------
+
+// Exact probability of drawing exactly k successes when drawing n cards
+// from a population of N cards containing K successes. n is capped by N,
+// drawing more than the population seeing every card.
+func HypergeometricPMF(N, K, n, k int) float64 {
+	if n > N {
+		n = N
+	}
+	if k < 0 || k > K || k > n || n-k > N-K {
+		return 0
+	}
+	num := new(big.Int).Mul(new(big.Int).Binomial(int64(K), int64(k)), new(big.Int).Binomial(int64(N-K), int64(n-k)))
+	den := new(big.Int).Binomial(int64(N), int64(n))
+	p, _ := new(big.Rat).SetFrac(num, den).Float64()
+	return p
+}
+
+// Probability of drawing at least k successes.
+func HypergeometricAtLeast(N, K, n, k int) float64 {
+	if k <= 0 {
+		return 1
+	}
+	p := 0.0
+	for i := k; i <= K && i <= n; i++ {
+		p += HypergeometricPMF(N, K, n, i)
+	}
+	return p
+}
+
+func HypergeometricMean(N, K, n int) float64 {
+	if N == 0 {
+		return 0
+	}
+	if n > N {
+		n = N
+	}
+	return float64(n) * float64(K) / float64(N)
+}
+
+// Draw Schedules -------------------------------------------------------------
+
+const HandSize = 6
+
+// Number of command cards seen at the end of each draw phase: the opening
+// hand is HandSize cards, then every draw phase refills the hand up to
+// HandSize. cardsSpentPerTurn is how many cards leave the hand each turn
+// (played, committed to edge stacks or discarded), which is what the next
+// draw phase replaces. The schedule is capped by the deck size.
+func DrawSchedule(turns int, cardsSpentPerTurn int, deckSize int) []int {
+	schedule := make([]int, turns)
+	seen := HandSize
+	for t := 0; t < turns; t++ {
+		if t > 0 {
+			spent := cardsSpentPerTurn
+			if spent > HandSize {
+				spent = HandSize
+			}
+			seen += spent
+		}
+		if seen > deckSize {
+			seen = deckSize
+		}
+		schedule[t] = seen
+	}
+	return schedule
+}
+
+// Deck Probabilities ---------------------------------------------------------
+
+func (d *Deck) CountCommandCards(predicate CardPredicate) int {
+	return len(FilterCards(d.CommandDeck(), predicate))
+}
+
+// Probability of having seen at least k matching command cards after
+// drawing the given number of cards.
+func (d *Deck) DrawProbability(predicate CardPredicate, drawn int, k int) float64 {
+	cards := d.CommandDeck()
+	return HypergeometricAtLeast(len(cards), len(FilterCards(cards, predicate)), drawn, k)
+}
+
+func (d *Deck) OpeningHandProbability(predicate CardPredicate, k int) float64 {
+	return d.DrawProbability(predicate, HandSize, k)
+}
+
+func (d *Deck) ExpectedDrawn(predicate CardPredicate, drawn int) float64 {
+	cards := d.CommandDeck()
+	return HypergeometricMean(len(cards), len(FilterCards(cards, predicate)), drawn)
+}
+
+type TurnDrawOdds struct {
+	Turn        int
+	CardsSeen   int
+	AtLeastOne  float64
+	Expected    float64
+	Probability []float64 // Probability[k] of having seen exactly k matching cards
+}
+
+// Per turn odds of seeing the matching cards, following DrawSchedule.
+func (d *Deck) DrawOddsByTurn(predicate CardPredicate, turns int, cardsSpentPerTurn int) []TurnDrawOdds {
+	cards := d.CommandDeck()
+	N, K := len(cards), len(FilterCards(cards, predicate))
+
+	odds := make([]TurnDrawOdds, 0, turns)
+	for t, seen := range DrawSchedule(turns, cardsSpentPerTurn, N) {
+		entry := TurnDrawOdds{Turn: t+1, CardsSeen: seen, Probability: make([]float64, K+1)}
+		for k := 0; k <= K; k++ {
+			entry.Probability[k] = HypergeometricPMF(N, K, seen, k)
+		}
+		entry.AtLeastOne = 1 - entry.Probability[0]
+		entry.Expected = HypergeometricMean(N, K, seen)
+		odds = append(odds, entry)
+	}
+	return odds
+}
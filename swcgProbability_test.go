@@ -0,0 +1,65 @@
+package swcg
+
+import "math"
+import "testing"
+
+func TestHypergeometric(t *testing.T) {
+	tests := []struct {
+		name     string
+		got      float64
+		expected float64
+	}{
+		{"exactly 1 ace in a poker hand", HypergeometricPMF(52, 4, 5, 1), 0.2994736356080894},
+		{"no ace in a poker hand", HypergeometricPMF(52, 4, 5, 0), 0.6588419983377967},
+		{"all 3 successes in 3 draws out of 10", HypergeometricPMF(10, 3, 3, 3), 1.0 / 120},
+		{"at least 1 of 4 copies in 7 cards of 60", HypergeometricAtLeast(60, 4, 7, 1), 0.3994996257446656},
+		{"at least 2 of 10 in 6 cards of 40", HypergeometricAtLeast(40, 10, 6, 2), 0.4740398293029872},
+		{"at least 0", HypergeometricAtLeast(40, 10, 6, 0), 1},
+		{"more successes than drawn", HypergeometricPMF(40, 10, 6, 7), 0},
+		{"more drawn than the population", HypergeometricPMF(5, 2, 6, 2), 1},
+		{"fewer successes than drawn when all are seen", HypergeometricPMF(5, 2, 6, 1), 0},
+		{"at least 1 when drawing more than the population", HypergeometricAtLeast(5, 2, 100, 1), 1},
+		{"mean", HypergeometricMean(50, 10, 6), 1.2},
+		{"mean capped by the population", HypergeometricMean(10, 5, 20), 5},
+	}
+	for _, test := range tests {
+		if math.Abs(test.got-test.expected) > 1e-12 {
+			t.Errorf("%s: got %v, expected %v", test.name, test.got, test.expected)
+		}
+	}
+}
+
+func TestHypergeometricSumsToOne(t *testing.T) {
+	total := 0.0
+	for k := 0; k <= 4; k++ {
+		total += HypergeometricPMF(50, 4, 6, k)
+	}
+	if math.Abs(total-1) > 1e-12 {
+		t.Errorf("PMF sums to %v", total)
+	}
+}
+
+func TestDrawSchedule(t *testing.T) {
+	schedule := DrawSchedule(5, 2, 12)
+	expected := []int{6, 8, 10, 12, 12}
+	for i := range expected {
+		if schedule[i] != expected[i] {
+			t.Fatalf("DrawSchedule(5, 2, 12) = %v, expected %v", schedule, expected)
+		}
+	}
+}
+
+func TestDrawProbabilityBeyondTheDeck(t *testing.T) {
+	_, cache := AnalyzeDB(CreateDB())
+	deck, err := CreateDeck(cache, Faction_Jedi, 1, 1, 2, 2, 3, 3, 4, 5, 6, 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	yoda := NamePredicate("Yoda")
+	if deck.CountCommandCards(yoda) == 0 {
+		t.Fatal("the deck should have Yoda")
+	}
+	if p := deck.DrawProbability(yoda, 100, 1); p != 1 {
+		t.Errorf("drawing past the whole deck should see Yoda, got %v", p)
+	}
+}
@@ -0,0 +1,461 @@
+package swcg
+
+import "fmt"
+import "sort"
+import "strconv"
+import "strings"
+
+// Card Queries ----------------------------------------------------------------
+//
+// Textual card filters compiled into a CardPredicate and sort keys:
+//
+//   query := or
+//   or    := and { 'or' and }
+//   and   := unary { ['and'] unary }
+//   unary := ('-' | 'not') unary | '(' or ')' | term
+//   term  := field op value | 'sort:' ['-']column {',' ['-']column} | word
+//   op    := ':' | '=' | '!=' | '<' | '<=' | '>' | '>='
+//
+// Fields are name (substring with ':', exact name with '='), faction, side,
+// type, trait, keyword, set (objective set id) and the numeric fields cost,
+// resources, force, health and number. Numeric fields take every operator,
+// the others ':', '=' and '!='. Values may be double quoted, names are
+// matched case insensitively and a bare word is a name substring, e.g.
+//
+//   faction:Jedi type:Unit cost<=3 trait:ForceUser -keyword:Elite sort:cost,-force
+//   (trait:Droid or name:"Luke") -set:18
+//
+// Sort columns are the CardColumns names, descending when prefixed with '-'.
+
+type QueryParseError struct {
+	Query string
+	Pos   int
+	Msg   string
+}
+func (e *QueryParseError) Error() string {
+	return fmt.Sprintf("card query %q, position %d: %s", e.Query, e.Pos, e.Msg)
+}
+
+type QuerySortKey struct {
+	Column     string
+	Descending bool
+}
+
+type CardQuery struct {
+	Query     string
+	Predicate CardPredicate
+	Sort      []QuerySortKey
+}
+
+var queryFields = []string{"name", "faction", "side", "type", "trait", "keyword", "set", "cost", "resources", "force", "health", "number", "sort"}
+
+func ParseCardQuery(query string) (*CardQuery, error) {
+	p := &queryParser{query: query}
+	q := &CardQuery{Query: query, Predicate: func(*Card) bool { return true }}
+	if p.skipSpaces(); p.pos == len(p.query) {
+		return q, nil
+	}
+	predicate, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpaces(); p.pos < len(p.query) {
+		return nil, p.errorf("unexpected %q", p.query[p.pos:])
+	}
+	if predicate != nil {
+		q.Predicate = predicate
+	}
+	q.Sort = p.sort
+	return q, nil
+}
+
+func MustParseCardQuery(query string) *CardQuery {
+	q, err := ParseCardQuery(query)
+	if err != nil {
+		panic(err.Error())
+	}
+	return q
+}
+
+func (q *CardQuery) Filter(cards []*Card) []*Card {
+	return FilterCards(cards, func(c *Card) bool { return c.Type != nil && q.Predicate(c) })
+}
+
+// Sort entries of the query for a DataCollection holding its sort columns.
+func (q *CardQuery) SortEntries(data *DataCollection) ([]RowSortEntry, error) {
+	entries := make([]RowSortEntry, 0, len(q.Sort))
+	for _, key := range q.Sort {
+		index := data.ColumnIndex(key.Column)
+		if index < 0 {
+			return nil, fmt.Errorf("no %s column to sort on", key.Column)
+		}
+		order := SortOrder_Ascending
+		if key.Descending {
+			order = SortOrder_Descending
+		}
+		entries = append(entries, CreateRowSortEntry(index, order))
+	}
+	return entries, nil
+}
+
+// Matching cards of the cache, ordered by card number then by the sort keys
+// of the query, with the given columns followed by the sort columns missing
+// from them.
+func (q *CardQuery) Run(cache *DataCache, columns []CardColumn) (*DataCollection, error) {
+	columns = append([]CardColumn(nil), columns...)
+	for _, key := range q.Sort {
+		present := false
+		for _, col := range columns {
+			present = present || strings.EqualFold(col.Name, key.Column)
+		}
+		if col, ok := CardColumnByName(key.Column); ok && !present {
+			columns = append(columns, col)
+		}
+	}
+	cards := make([]*Card, 0, len(*cache.CardMap))
+	for _, c := range *cache.CardMap {
+		cards = append(cards, c)
+	}
+	sort.Slice(cards, func(i, j int) bool { return cards[i].Number < cards[j].Number })
+
+	data := CardDataCollection(q.Filter(cards), columns)
+	if len(q.Sort) > 0 {
+		entries, err := q.SortEntries(data)
+		if err != nil {
+			return nil, err
+		}
+		data.Sort(entries)
+	}
+	return data, nil
+}
+
+// Suggestions ----------------------------------------------------------------
+
+func editDistance(a, b string) int {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// Closest name to word, "" when none is close enough to be a likely typo.
+func suggestName(word string, names []string) string {
+	best, bestDistance := "", max(2, len(word)/3)+1
+	for _, name := range names {
+		if d := editDistance(word, name); d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+	return best
+}
+
+// "unknown trait "x"", followed by a suggestion or the list of the names.
+func unknownNameMessage(what, word string, names []string) string {
+	if suggestion := suggestName(word, names); suggestion != "" {
+		return fmt.Sprintf("unknown %s %q, did you mean %q?", what, word, suggestion)
+	}
+	return fmt.Sprintf("unknown %s %q (expected one of %s)", what, word, strings.Join(names, ", "))
+}
+
+// Parsing --------------------------------------------------------------------
+
+type queryParser struct {
+	query string
+	pos   int
+	sort  []QuerySortKey
+}
+
+func (p *queryParser) errorf(format string, args ...interface{}) error {
+	return &QueryParseError{Query: p.query, Pos: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *queryParser) skipSpaces() {
+	for p.pos < len(p.query) && strings.ContainsRune(" \t\n\r", rune(p.query[p.pos])) {
+		p.pos++
+	}
+}
+
+func isQueryWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
+
+func (p *queryParser) ident() string {
+	start := p.pos
+	for p.pos < len(p.query) && isQueryWordByte(p.query[p.pos]) {
+		p.pos++
+	}
+	return p.query[start:p.pos]
+}
+
+// Reads a keyword (or, and, not) if one is next.
+func (p *queryParser) keyword(word string) bool {
+	p.skipSpaces()
+	start := p.pos
+	if strings.EqualFold(p.ident(), word) && (p.pos == len(p.query) || !strings.ContainsRune(":=!<>", rune(p.query[p.pos]))) {
+		return true
+	}
+	p.pos = start
+	return false
+}
+
+// Predicates are nil for terms that don't filter, i.e. sort keys.
+func andPredicates(a, b CardPredicate) CardPredicate {
+	switch {
+	case a == nil: return b
+	case b == nil: return a
+	}
+	return func(c *Card) bool { return a(c) && b(c) }
+}
+
+func (p *queryParser) parseOr() (CardPredicate, error) {
+	predicate, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if predicate == nil || right == nil {
+			return nil, p.errorf("sort keys can't be part of an or")
+		}
+		predicate = AnyPredicate(predicate, right)
+	}
+	return predicate, nil
+}
+
+func (p *queryParser) parseAnd() (CardPredicate, error) {
+	predicate, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if p.skipSpaces(); p.pos == len(p.query) || p.query[p.pos] == ')' {
+			return predicate, nil
+		}
+		start := p.pos
+		if p.keyword("or") {
+			p.pos = start
+			return predicate, nil
+		}
+		p.keyword("and")
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		predicate = andPredicates(predicate, right)
+	}
+}
+
+func (p *queryParser) parseUnary() (CardPredicate, error) {
+	p.skipSpaces()
+	if p.pos == len(p.query) {
+		return nil, p.errorf("expected a filter but reached the end of the query")
+	}
+	start := p.pos
+	negated := false
+	if p.query[p.pos] == '-' {
+		p.pos++
+		negated = true
+	} else if p.keyword("not") {
+		negated = true
+	}
+	if negated {
+		predicate, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if predicate == nil {
+			p.pos = start
+			return nil, p.errorf("sort keys can't be negated")
+		}
+		return func(c *Card) bool { return !predicate(c) }, nil
+	}
+
+	if p.query[p.pos] == '(' {
+		p.pos++
+		predicate, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.skipSpaces(); p.pos == len(p.query) || p.query[p.pos] != ')' {
+			return nil, p.errorf("expected ')' to close the '(' at position %d", start)
+		}
+		p.pos++
+		return predicate, nil
+	}
+	return p.parseTerm()
+}
+
+func (p *queryParser) operator() string {
+	for _, op := range []string{"<=", ">=", "!=", ":", "=", "<", ">"} {
+		if strings.HasPrefix(p.query[p.pos:], op) {
+			p.pos += len(op)
+			return op
+		}
+	}
+	return ""
+}
+
+func (p *queryParser) value() (string, error) {
+	if p.pos < len(p.query) && p.query[p.pos] == '"' {
+		end := strings.IndexByte(p.query[p.pos+1:], '"')
+		if end < 0 {
+			return "", p.errorf("unterminated quoted value")
+		}
+		value := p.query[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return value, nil
+	}
+	start := p.pos
+	for p.pos < len(p.query) && !strings.ContainsRune(" \t\n\r()", rune(p.query[p.pos])) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("expected a value")
+	}
+	return p.query[start:p.pos], nil
+}
+
+func (p *queryParser) parseTerm() (CardPredicate, error) {
+	start := p.pos
+	field := p.ident()
+	op := ""
+	if field != "" {
+		op = p.operator()
+	}
+	if op == "" {
+		// bare word: name substring
+		p.pos = start
+		word, err := p.value()
+		if err != nil {
+			return nil, p.errorf("expected a filter but found %q", p.query[p.pos:p.pos+1])
+		}
+		return nameContains(word), nil
+	}
+	valueStart := p.pos
+	value, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	fail := func(format string, args ...interface{}) (CardPredicate, error) {
+		p.pos = valueStart
+		return nil, p.errorf(format, args...)
+	}
+
+	field = strings.ToLower(field)
+	switch field {
+	case "sort":
+		if op != ":" && op != "=" {
+			return fail("sort expects ':'")
+		}
+		for _, key := range strings.Split(value, ",") {
+			sortKey := QuerySortKey{Column: strings.TrimPrefix(key, "-"), Descending: strings.HasPrefix(key, "-")}
+			col, ok := CardColumnByName(sortKey.Column)
+			if !ok {
+				return fail("%s", unknownNameMessage("sort column", sortKey.Column, CardColumnNames()))
+			}
+			sortKey.Column = col.Name
+			p.sort = append(p.sort, sortKey)
+		}
+		return nil, nil
+	case "cost", "resources", "force", "health", "number":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fail("%s expects a number, not %q", field, value)
+		}
+		return numericPredicate(field, op, n), nil
+	}
+
+	var predicate CardPredicate
+	switch field {
+	case "name":
+		if op == "=" {
+			predicate = NamePredicate(value)
+		} else {
+			predicate = nameContains(value)
+		}
+	case "faction", "side", "type", "trait", "keyword":
+		names := map[string][]string{"faction": FactionNames[:], "side": SideNames[:], "type": CardTypeNames[:],
+			"trait": TraitNames[:], "keyword": KeywordNames[:]}[field]
+		index := lookupNameFold(names, value)
+		if index < 0 {
+			return fail("%s", unknownNameMessage(field, value, names))
+		}
+		switch field {
+		case "faction": predicate = func(c *Card) bool { return c.Faction == CardFaction(index) }
+		case "side":    predicate = func(c *Card) bool { return c.Faction.Side() == CardSide(index) }
+		case "type":    predicate = TypePredicate(CardType(index))
+		case "trait":   predicate = TraitPredicate(CardTraitType(index))
+		case "keyword": predicate = KeywordPredicate(CardKeywordType(index))
+		}
+	case "set":
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return fail("set expects an objective set id, not %q", value)
+		}
+		predicate = func(c *Card) bool {
+			for _, s := range c.ObjectiveSets {
+				if s.SetId == id {
+					return true
+				}
+			}
+			return false
+		}
+	default:
+		p.pos = start
+		return nil, p.errorf("%s", unknownNameMessage("field", field, queryFields))
+	}
+
+	switch op {
+	case ":", "=":
+		return predicate, nil
+	case "!=":
+		return func(c *Card) bool { return !predicate(c) }, nil
+	}
+	p.pos = valueStart - len(op)
+	return nil, p.errorf("%s only supports ':', '=' and '!='", field)
+}
+
+func nameContains(s string) CardPredicate {
+	s = strings.ToLower(s)
+	return func(c *Card) bool { return strings.Contains(strings.ToLower(c.Name), s) }
+}
+
+func numericPredicate(field, op string, n int) CardPredicate {
+	value := func(c *Card) int {
+		switch field {
+		case "cost":      return c.Cost
+		case "resources": return c.Ressources
+		case "force":     return c.ForceIcons
+		case "health":    return c.Health
+		}
+		return c.Number
+	}
+	return func(c *Card) bool {
+		v := value(c)
+		switch op {
+		case "<":  return v < n
+		case "<=": return v <= n
+		case ">":  return v > n
+		case ">=": return v >= n
+		case "!=": return v != n
+		}
+		return v == n
+	}
+}
@@ -0,0 +1,70 @@
+package swcg
+
+import "strings"
+import "testing"
+
+func TestParseCardQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+		msg   string
+	}{
+		{"cost<=x", 6, "cost expects a number"},
+		{"trait:Droud", 6, `did you mean "Droid"`},
+		{"colour:red", 0, "unknown field"},
+		{"faction<Jedi", 7, "faction only supports ':', '=' and '!='"},
+		{"(type:Unit", 10, "expected ')' to close the '(' at position 0"},
+		{"type:Unit)", 9, "unexpected \")\""},
+		{"name:\"Luke", 5, "unterminated quoted value"},
+		{"cost:", 5, "expected a value"},
+		{"type:Unit and", 13, "reached the end of the query"},
+		{"-sort:cost", 0, "sort keys can't be negated"},
+		{"sort:cost or type:Unit", 22, "sort keys can't be part of an or"},
+		{"sort<cost", 5, "sort expects ':'"},
+		{"sort:cost,-forse", 5, "unknown sort column"},
+	}
+	for _, test := range tests {
+		_, err := ParseCardQuery(test.query)
+		parseErr, ok := err.(*QueryParseError)
+		if !ok {
+			t.Errorf("%q: expected a QueryParseError, got %v", test.query, err)
+			continue
+		}
+		if parseErr.Pos != test.pos || !strings.Contains(parseErr.Msg, test.msg) {
+			t.Errorf("%q: got %q at %d, expected %q at %d", test.query, parseErr.Msg, parseErr.Pos, test.msg, test.pos)
+		}
+	}
+}
+
+func TestParseCardQueryFilters(t *testing.T) {
+	cards := []*Card{
+		{Name: "Luke Skywalker", Faction: Faction_Jedi, Type: Type(CardType_Unit), Cost: 4, Abilities: AbilityList{Trait(Trait_Character)}},
+		{Name: "R2-D2", Faction: Faction_LightNeutral, Type: Type(CardType_Unit), Cost: 1, Abilities: AbilityList{Trait(Trait_Droid)}},
+		{Name: "Counter-Stroke", Faction: Faction_Jedi, Type: Type(CardType_Event), Cost: 1},
+	}
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"", []string{"Luke Skywalker", "R2-D2", "Counter-Stroke"}},
+		{"type:unit cost>=2", []string{"Luke Skywalker"}},
+		{"faction:Jedi -type:Event", []string{"Luke Skywalker"}},
+		{"trait:Droid or name:\"counter\"", []string{"R2-D2", "Counter-Stroke"}},
+		{"not (cost=1 and type!=Event)", []string{"Luke Skywalker", "Counter-Stroke"}},
+		{"sky sort:-cost", []string{"Luke Skywalker"}},
+	}
+	for _, test := range tests {
+		q, err := ParseCardQuery(test.query)
+		if err != nil {
+			t.Errorf("%q: %v", test.query, err)
+			continue
+		}
+		names := make([]string, 0)
+		for _, c := range q.Filter(cards) {
+			names = append(names, c.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("%q: got %v, expected %v", test.query, names, test.expected)
+		}
+	}
+}
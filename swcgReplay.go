@@ -0,0 +1,322 @@
+package swcg
+
+import "bufio"
+import "encoding/json"
+import "fmt"
+import "io"
+import "strings"
+
+// Replay Log -----------------------------------------------------------------
+//
+// JSON Lines log of a game: a header record with the seed and both deck
+// lists, one record per move in the order they were played and a result
+// record once the game is over. Games being deterministic, replaying the
+// moves from the header rebuilds every intermediate state.
+
+const ReplayVersion = 1
+
+type ReplayDeck struct {
+	Faction string `json:"faction"`
+	SetIds  []int  `json:"sets"`
+}
+
+type ReplayHeader struct {
+	Version int           `json:"version"`
+	Seed    uint64        `json:"seed"`
+	Decks   [2]ReplayDeck `json:"decks"` // dark side deck first
+}
+
+type ReplayMove struct {
+	Step         int    `json:"step"`
+	Turn         int    `json:"turn"`
+	Phase        string `json:"phase"`
+	Move         string `json:"move"`
+	Player       int    `json:"player"`
+	Card         int    `json:"card"`
+	CardNumber   int    `json:"cardNumber,omitempty"`
+	Target       int    `json:"target"`
+	TargetNumber int    `json:"targetNumber,omitempty"`
+}
+
+type ReplayResult struct {
+	Winner        int `json:"winner"`
+	Turns         int `json:"turns"`
+	DeathStarDial int `json:"deathStarDial"`
+}
+
+type replayRecord struct {
+	Type   string        `json:"type"` // header | move | result
+	Header *ReplayHeader `json:"header,omitempty"`
+	Move   *ReplayMove   `json:"move,omitempty"`
+	Result *ReplayResult `json:"result,omitempty"`
+}
+
+// Writing --------------------------------------------------------------------
+
+type ReplayWriter struct {
+	encoder *json.Encoder
+	step    int
+}
+
+// Starts the log of the game with its header, before any move is played.
+func NewReplayWriter(w io.Writer, g *Game) (*ReplayWriter, error) {
+	header := &ReplayHeader{Version: ReplayVersion, Seed: g.Seed}
+	for i, p := range g.Players {
+		header.Decks[i] = ReplayDeck{Faction: FactionNames[p.Deck.Faction], SetIds: append([]int(nil), p.Deck.SetIds...)}
+	}
+	writer := &ReplayWriter{encoder: json.NewEncoder(w)}
+	return writer, writer.encoder.Encode(replayRecord{Type: "header", Header: header})
+}
+
+// Logs the move, called before applying it to the game.
+func (rw *ReplayWriter) WriteMove(g *Game, m Move) error {
+	rw.step++
+	record := &ReplayMove{Step: rw.step, Turn: g.Turn, Phase: PhaseNames[g.Phase], Move: MoveNames[m.Type],
+		Player: m.Player, Card: m.Card, Target: m.Target}
+	if m.Card != NoCard {
+		record.CardNumber = g.Card(m.Card).Card.Number
+	}
+	if m.Target != NoCard {
+		record.TargetNumber = g.Card(m.Target).Card.Number
+	}
+	return rw.encoder.Encode(replayRecord{Type: "move", Move: record})
+}
+
+func (rw *ReplayWriter) WriteResult(g *Game) error {
+	return rw.encoder.Encode(replayRecord{Type: "result", Result: &ReplayResult{Winner: g.Winner, Turns: g.Turn, DeathStarDial: g.DeathStarDial}})
+}
+
+// Plays a full game like PlayGame, logging it to w.
+func PlayLoggedGame(dark, light *Deck, players [2]Player, seed uint64, w io.Writer) (*Game, error) {
+	g := NewGame(dark, light, seed)
+	log, err := NewReplayWriter(w, g)
+	if err != nil {
+		return g, err
+	}
+	if err := playGame(g, players, log); err != nil {
+		return g, err
+	}
+	return g, log.WriteResult(g)
+}
+
+// Loading --------------------------------------------------------------------
+
+type Replay struct {
+	Header ReplayHeader
+	Moves  []ReplayMove
+	Result *ReplayResult // nil for an unfinished game
+}
+
+func LoadReplay(r io.Reader) (*Replay, error) {
+	replay := &Replay{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var record replayRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("replay line %d: %v", line, err)
+		}
+		switch {
+		case line == 1 && (record.Type != "header" || record.Header == nil):
+			return nil, fmt.Errorf("replay line 1: expected a header record")
+		case record.Type == "header" && record.Header != nil:
+			if record.Header.Version != ReplayVersion {
+				return nil, fmt.Errorf("unsupported replay version %d (expected %d)", record.Header.Version, ReplayVersion)
+			}
+			replay.Header = *record.Header
+		case record.Type == "move" && record.Move != nil:
+			if record.Move.Step != len(replay.Moves)+1 {
+				return nil, fmt.Errorf("replay line %d: expected step %d, got %d", line, len(replay.Moves)+1, record.Move.Step)
+			}
+			if err := record.Move.checkIds(-1); err != nil {
+				return nil, fmt.Errorf("replay line %d: %v", line, err)
+			}
+			replay.Moves = append(replay.Moves, *record.Move)
+		case record.Type == "result" && record.Result != nil:
+			replay.Result = record.Result
+		default:
+			return nil, fmt.Errorf("replay line %d: invalid %q record", line, record.Type)
+		}
+	}
+	return replay, scanner.Err()
+}
+
+func (replay *Replay) NewGame(cache *DataCache) (*Game, error) {
+	var decks [2]*Deck
+	for i, d := range replay.Header.Decks {
+		faction, err := enumValue(FactionNames[:], d.Faction, "faction")
+		if err != nil {
+			return nil, err
+		}
+		if decks[i], err = CreateDeck(cache, CardFaction(faction), d.SetIds...); err != nil {
+			return nil, err
+		}
+	}
+	return NewGame(decks[0], decks[1], replay.Header.Seed), nil
+}
+
+func (rm *ReplayMove) toMove() (Move, error) {
+	t, err := enumValue(MoveNames[:], rm.Move, "move")
+	return Move{Type: MoveType(t), Player: rm.Player, Card: rm.Card, Target: rm.Target}, err
+}
+
+// Checks the player is Player_Dark or Player_Light and the card ids are
+// NoCard or below cards (when not negative).
+func (rm *ReplayMove) checkIds(cards int) error {
+	if rm.Player != Player_Dark && rm.Player != Player_Light {
+		return fmt.Errorf("invalid player %d", rm.Player)
+	}
+	for _, id := range []int{rm.Card, rm.Target} {
+		if id < NoCard || cards >= 0 && id >= cards {
+			return fmt.Errorf("invalid card id %d", id)
+		}
+	}
+	return nil
+}
+
+// Checks the recorded move can be played in the rebuilt game: valid ids
+// and the logged cards being the ones of the game.
+func (replay *Replay) check(g *Game, step int) error {
+	rm := &replay.Moves[step]
+	if err := rm.checkIds(len(g.Cards)); err != nil {
+		return fmt.Errorf("step %d: %v", rm.Step, err)
+	}
+	if rm.Card != NoCard && g.Card(rm.Card).Card.Number != rm.CardNumber ||
+		rm.Target != NoCard && g.Card(rm.Target).Card.Number != rm.TargetNumber {
+		return fmt.Errorf("step %d: the replay doesn't match the game's cards", rm.Step)
+	}
+	return nil
+}
+
+// Plays the next recorded move, checked first.
+func (replay *Replay) apply(g *Game, step int) error {
+	if err := replay.check(g, step); err != nil {
+		return err
+	}
+	rm := &replay.Moves[step]
+	m, err := rm.toMove()
+	if err != nil {
+		return fmt.Errorf("step %d: %v", rm.Step, err)
+	}
+	if err := g.Apply(m); err != nil {
+		return fmt.Errorf("step %d: %v", rm.Step, err)
+	}
+	return nil
+}
+
+// Game state after the first step moves.
+func (replay *Replay) StateAt(cache *DataCache, step int) (*Game, error) {
+	if step < 0 || step > len(replay.Moves) {
+		return nil, fmt.Errorf("step %d out of range [0, %d]", step, len(replay.Moves))
+	}
+	g, err := replay.NewGame(cache)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < step; i++ {
+		if err := replay.apply(g, i); err != nil {
+			return g, err
+		}
+	}
+	return g, nil
+}
+
+// Narration ------------------------------------------------------------------
+
+var replayPlayerNames = [2]string{"Dark side", "Light side"}
+
+// Text account of the game, one line per move and per noteworthy
+// consequence (turns, damage on objectives, destroyed cards, the winner).
+func (replay *Replay) Narrate(w io.Writer, cache *DataCache) error {
+	g, err := replay.NewGame(cache)
+	if err != nil {
+		return err
+	}
+	name := func(number int) string {
+		if c, ok := (*cache.CardMap)[number]; ok {
+			return c.Name
+		}
+		return fmt.Sprintf("card #%d", number)
+	}
+	out := bufio.NewWriter(w)
+	defer out.Flush()
+	turn := 0
+	for i := range replay.Moves {
+		if g.Turn != turn {
+			turn = g.Turn
+			fmt.Fprintf(out, "--- Turn %d: %s, Death Star dial %d, the Force is with the %s side\n",
+				g.Turn, replayPlayerNames[g.Active], g.DeathStarDial, strings.ToLower(SideNames[g.BalanceOfTheForce]))
+		}
+		if err := replay.check(g, i); err != nil {
+			return err
+		}
+		rm := &replay.Moves[i]
+		fmt.Fprintf(out, "%4d %s %s\n", rm.Step, replayPlayerNames[rm.Player], describeMove(g, rm, name))
+
+		before := g.Clone()
+		if err := replay.apply(g, i); err != nil {
+			return err
+		}
+		for _, line := range describeChanges(before, g, name) {
+			fmt.Fprintln(out, "     "+line)
+		}
+	}
+	if g.IsOver() {
+		fmt.Fprintf(out, "%s wins on turn %d\n", replayPlayerNames[g.Winner], g.Turn)
+	}
+	return nil
+}
+
+func describeMove(g *Game, rm *ReplayMove, name func(int) string) string {
+	target := ""
+	if rm.Target != NoCard {
+		target = name(rm.TargetNumber)
+	}
+	switch rm.Move {
+	case "PlayCard":
+		if target != "" {
+			return "plays "+name(rm.CardNumber)+" on "+target
+		}
+		return "plays "+name(rm.CardNumber)
+	case "UseAbility":
+		if target != "" {
+			return "uses "+name(rm.CardNumber)+" on "+target
+		}
+		return "uses "+name(rm.CardNumber)
+	case "Engage":         return "engages "+target
+	case "CommitAttacker": return "attacks with "+name(rm.CardNumber)
+	case "CommitDefender": return "defends with "+name(rm.CardNumber)
+	case "EdgeCard":       return "places "+name(rm.CardNumber)+" in the edge stack"
+	case "CommitToForce":  return "commits "+name(rm.CardNumber)+" to the Force"
+	case "Pass":           return "passes ("+StepNames[g.Step]+")"
+	}
+	return rm.Move
+}
+
+func describeChanges(before, after *Game, name func(int) string) []string {
+	lines := make([]string, 0)
+	if after.DeathStarDial != before.DeathStarDial {
+		lines = append(lines, fmt.Sprintf("the Death Star dial advances to %d", after.DeathStarDial))
+	}
+	if after.BalanceOfTheForce != before.BalanceOfTheForce {
+		lines = append(lines, "the Force is now with the "+strings.ToLower(SideNames[after.BalanceOfTheForce])+" side")
+	}
+	for id := range before.Cards {
+		b, a := &before.Cards[id], &after.Cards[id]
+		cardName := name(a.Card.Number)
+		switch {
+		case b.Zone == Zone_Objectives && a.Zone == Zone_Destroyed:
+			lines = append(lines, cardName+" is destroyed")
+		case b.Zone == Zone_Play && a.Zone == Zone_Discard && b.Card.Type.GetType() == CardType_Unit:
+			lines = append(lines, cardName+" is discarded")
+		case b.Zone == Zone_Play && a.Zone == Zone_Hand:
+			lines = append(lines, cardName+" returns to its owner's hand")
+		case a.Zone == b.Zone && a.Damage > b.Damage:
+			lines = append(lines, fmt.Sprintf("%s takes %d damage (%d/%d)", cardName, a.Damage-b.Damage, a.Damage, a.Card.Health))
+		}
+	}
+	return lines
+}
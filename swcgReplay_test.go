@@ -0,0 +1,71 @@
+package swcg
+
+import "bytes"
+import "io"
+import "strings"
+import "testing"
+
+func TestReplayRoundTrip(t *testing.T) {
+	dark, light := testDecks(t)
+	_, cache := AnalyzeDB(CreateDB())
+	for seed := uint64(1); seed <= 10; seed++ {
+		var log bytes.Buffer
+		g, err := PlayLoggedGame(dark, light, [2]Player{NewGreedyPlayer(seed), NewRandomPlayer(seed)}, seed, &log)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		replay, err := LoadReplay(&log)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		if replay.Result == nil || replay.Result.Winner != g.Winner || replay.Result.Turns != g.Turn {
+			t.Errorf("seed %d: logged result %+v", seed, replay.Result)
+		}
+		replayed, err := replay.StateAt(cache, len(replay.Moves))
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		if replayed.Winner != g.Winner || replayed.Turn != g.Turn || replayed.DeathStarDial != g.DeathStarDial {
+			t.Errorf("seed %d: replayed winner %d, turn %d, dial %d, expected %d, %d, %d", seed,
+				replayed.Winner, replayed.Turn, replayed.DeathStarDial, g.Winner, g.Turn, g.DeathStarDial)
+		}
+		if err := replay.Narrate(io.Discard, cache); err != nil {
+			t.Errorf("seed %d: %v", seed, err)
+		}
+	}
+}
+
+func TestMalformedReplays(t *testing.T) {
+	dark, light := testDecks(t)
+	_, cache := AnalyzeDB(CreateDB())
+	var log bytes.Buffer
+	if _, err := PlayLoggedGame(dark, light, [2]Player{NewRandomPlayer(1), NewRandomPlayer(2)}, 1, &log); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitN(log.String(), "\n", 3)
+	header, move := lines[0], lines[1]
+	if !strings.Contains(move, `"player":0`) || !strings.Contains(move, `"card":`) {
+		t.Fatalf("unexpected first move %s", move)
+	}
+	withMove := func(old, new string) string {
+		return header+"\n"+strings.Replace(move, old, new, 1)+"\n"
+	}
+	cardField := move[strings.Index(move, `"card":`):]
+	cardField = cardField[:strings.IndexAny(cardField, ",}")]
+
+	for _, bad := range []string{withMove(`"player":0`, `"player":2`), withMove(cardField, `"card":-5`)} {
+		if _, err := LoadReplay(strings.NewReader(bad)); err == nil {
+			t.Errorf("loading %q should fail", bad)
+		}
+	}
+	replay, err := LoadReplay(strings.NewReader(withMove(cardField, `"card":100000`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := replay.StateAt(cache, 1); err == nil {
+		t.Errorf("replaying an unknown card id should fail")
+	}
+	if err := replay.Narrate(io.Discard, cache); err == nil {
+		t.Errorf("narrating an unknown card id should fail")
+	}
+}
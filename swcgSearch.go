@@ -0,0 +1,377 @@
+package swcg
+
+import "math"
+import "sort"
+import "strings"
+import "unicode"
+
+// Full-Text Search -----------------------------------------------------------
+//
+// Inverted index of the card names, keywords, traits, ability descriptions
+// and quotes, built by AnalyzeDB. Words are lowercased and stemmed (Porter)
+// so that "shields" finds "Shielding" and "damaged" finds "damage". Queries
+// are lists of words and double quoted phrases, e.g. `shield "edge stack"`;
+// a card matches when it contains any of them and cards are ranked with
+// BM25.
+
+// Stemming
+
+type stemmer struct {
+	b []byte
+}
+
+func (s *stemmer) cons(i int) bool {
+	switch s.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !s.cons(i-1)
+	}
+	return true
+}
+
+// Number of vowel-consonant sequences in the first k letters.
+func (s *stemmer) measure(k int) int {
+	n, i := 0, 0
+	for i < k && s.cons(i) {
+		i++
+	}
+	for i < k {
+		for i < k && !s.cons(i) {
+			i++
+		}
+		if i >= k {
+			break
+		}
+		for i < k && s.cons(i) {
+			i++
+		}
+		n++
+	}
+	return n
+}
+
+func (s *stemmer) vowelIn(k int) bool {
+	for i := 0; i < k; i++ {
+		if !s.cons(i) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *stemmer) doubleCons(k int) bool {
+	return k >= 2 && s.b[k-1] == s.b[k-2] && s.cons(k-1)
+}
+
+// Consonant-vowel-consonant ending the first k letters, the last one not
+// being w, x or y.
+func (s *stemmer) cvc(k int) bool {
+	if k < 3 || !s.cons(k-3) || s.cons(k-2) || !s.cons(k-1) {
+		return false
+	}
+	c := s.b[k-1]
+	return c != 'w' && c != 'x' && c != 'y'
+}
+
+func (s *stemmer) ends(suffix string) bool { return strings.HasSuffix(string(s.b), suffix) }
+
+func (s *stemmer) replace(suffix, with string) {
+	s.b = append(s.b[:len(s.b)-len(suffix)], with...)
+}
+
+func (s *stemmer) step1() {
+	switch {
+	case s.ends("sses"), s.ends("ies"):
+		s.replace("es", "")
+	case s.ends("ss"), s.ends("us"):
+	case s.ends("s"):
+		s.replace("s", "")
+	}
+
+	if s.ends("eed") {
+		if s.measure(len(s.b)-3) > 0 {
+			s.replace("d", "")
+		}
+	} else {
+		for _, suffix := range []string{"ed", "ing"} {
+			if !s.ends(suffix) || !s.vowelIn(len(s.b)-len(suffix)) {
+				continue
+			}
+			s.replace(suffix, "")
+			k := len(s.b)
+			switch {
+			case s.ends("at"), s.ends("bl"), s.ends("iz"):
+				s.b = append(s.b, 'e')
+			case s.doubleCons(k) && !strings.ContainsRune("lsz", rune(s.b[k-1])):
+				s.b = s.b[:k-1]
+			case s.measure(k) == 1 && s.cvc(k):
+				s.b = append(s.b, 'e')
+			}
+			break
+		}
+	}
+
+	if s.ends("y") && s.vowelIn(len(s.b)-1) {
+		s.b[len(s.b)-1] = 'i'
+	}
+}
+
+// Applies the rule of the longest matching suffix when the remaining stem
+// measure is above minMeasure.
+func (s *stemmer) applyRules(rules [][2]string, minMeasure int) {
+	for _, r := range rules {
+		if s.ends(r[0]) {
+			k := len(s.b) - len(r[0])
+			if s.measure(k) > minMeasure && (r[0] != "ion" || k > 0 && (s.b[k-1] == 's' || s.b[k-1] == 't')) {
+				s.replace(r[0], r[1])
+			}
+			return
+		}
+	}
+}
+
+func longestFirst(rules [][2]string) [][2]string {
+	sort.SliceStable(rules, func(i, j int) bool { return len(rules[i][0]) > len(rules[j][0]) })
+	return rules
+}
+
+var stemStep2 = longestFirst([][2]string{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"}, {"izer", "ize"},
+	{"abli", "able"}, {"alli", "al"}, {"entli", "ent"}, {"eli", "e"}, {"ousli", "ous"},
+	{"ization", "ize"}, {"ation", "ate"}, {"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"},
+	{"fulness", "ful"}, {"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+})
+var stemStep3 = longestFirst([][2]string{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"}, {"ical", "ic"}, {"ful", ""}, {"ness", ""},
+})
+var stemStep4 = longestFirst([][2]string{
+	{"al", ""}, {"ance", ""}, {"ence", ""}, {"er", ""}, {"ic", ""}, {"able", ""}, {"ible", ""}, {"ant", ""},
+	{"ement", ""}, {"ment", ""}, {"ent", ""}, {"ion", ""}, {"ou", ""}, {"ism", ""}, {"ate", ""}, {"iti", ""},
+	{"ous", ""}, {"ive", ""}, {"ize", ""},
+})
+
+// Porter stem of a lowercase English word, "us" endings being kept as in
+// the later Porter2 algorithm ("focus" and "focused" both stem to "focus").
+func StemWord(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	s := &stemmer{b: []byte(word)}
+	s.step1()
+	s.applyRules(stemStep2, 0)
+	s.applyRules(stemStep3, 0)
+	s.applyRules(stemStep4, 1)
+	if s.ends("e") {
+		k := len(s.b) - 1
+		if m := s.measure(k); m > 1 || m == 1 && !s.cvc(k) {
+			s.b = s.b[:k]
+		}
+	}
+	if k := len(s.b); s.measure(k) > 1 && s.doubleCons(k) && s.b[k-1] == 'l' {
+		s.b = s.b[:k-1]
+	}
+	return string(s.b)
+}
+
+// Tokenization
+
+// Lowercase words of the text, without possessive "'s" nor apostrophes.
+func tokenizeText(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '’'
+	})
+	tokens := make([]string, 0, len(words))
+	for _, w := range words {
+		w = strings.TrimSuffix(strings.TrimSuffix(w, "'s"), "’s")
+		w = strings.NewReplacer("'", "", "’", "").Replace(w)
+		if w != "" {
+			tokens = append(tokens, w)
+		}
+	}
+	return tokens
+}
+
+// Stemmed terms of the text, in order.
+func TextTerms(text string) []string {
+	tokens := tokenizeText(text)
+	for i, t := range tokens {
+		tokens[i] = StemWord(t)
+	}
+	return tokens
+}
+
+// Text Index
+
+// Positions of a term in the text of a card.
+type textPosting struct {
+	card      int
+	positions []int
+}
+
+type TextIndex struct {
+	postings  map[string][]textPosting // by card number
+	lengths   map[int]int
+	avgLength float64
+	cards     *CardMap
+}
+
+// Gap left between the fields of a card so phrases don't span them.
+const textFieldGap = 8
+
+// "TargetedStrike" -> "Targeted Strike"
+func splitCamelCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Searchable text of the card: its name, keywords, traits, ability
+// descriptions and quote.
+func CardText(c *Card) []string {
+	fields := []string{c.Name}
+	for _, ability := range c.Abilities {
+		switch a := ability.(type) {
+		case KeywordInterface:
+			fields = append(fields, splitCamelCase(KeywordNames[a.GetKeyword()]))
+		case *CardTrait:
+			fields = append(fields, splitCamelCase(TraitNames[a.Trait]))
+		case *CardAbility:
+			if a.Description != "" {
+				fields = append(fields, a.Description)
+			}
+		}
+	}
+	if c.Quote != "" {
+		fields = append(fields, c.Quote)
+	}
+	return fields
+}
+
+func BuildTextIndex(cards *CardMap) *TextIndex {
+	index := &TextIndex{postings: make(map[string][]textPosting), lengths: make(map[int]int), cards: cards}
+	numbers := make([]int, 0, len(*cards))
+	for number := range *cards {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	total := 0
+	for _, number := range numbers {
+		positions := make(map[string][]int)
+		terms := make([]string, 0)
+		pos := 0
+		for _, field := range CardText((*cards)[number]) {
+			for _, term := range TextTerms(field) {
+				if _, ok := positions[term]; !ok {
+					terms = append(terms, term)
+				}
+				positions[term] = append(positions[term], pos)
+				pos++
+				index.lengths[number]++
+			}
+			pos += textFieldGap
+		}
+		for _, term := range terms {
+			index.postings[term] = append(index.postings[term], textPosting{card: number, positions: positions[term]})
+		}
+		total += index.lengths[number]
+	}
+	if len(numbers) > 0 {
+		index.avgLength = float64(total) / float64(len(numbers))
+	}
+	return index
+}
+
+// Search
+
+type SearchResult struct {
+	Card  *Card
+	Score float64
+}
+
+// Words and double quoted phrases of a query, as stemmed terms.
+func parseTextQuery(query string) [][]string {
+	clauses := make([][]string, 0)
+	for i, part := range strings.Split(query, `"`) {
+		if i%2 == 1 {
+			if terms := TextTerms(part); len(terms) > 0 {
+				clauses = append(clauses, terms)
+			}
+			continue
+		}
+		for _, term := range TextTerms(part) {
+			clauses = append(clauses, []string{term})
+		}
+	}
+	return clauses
+}
+
+// Number of occurrences of the phrase in each card.
+func (index *TextIndex) phraseFrequencies(phrase []string) map[int]int {
+	frequencies := make(map[int]int)
+	if len(phrase) == 1 {
+		for _, p := range index.postings[phrase[0]] {
+			frequencies[p.card] = len(p.positions)
+		}
+		return frequencies
+	}
+	rest := make([]map[int][]int, len(phrase)) // card -> positions, for the terms after the first
+	for i, term := range phrase[1:] {
+		rest[i+1] = make(map[int][]int)
+		for _, p := range index.postings[term] {
+			rest[i+1][p.card] = p.positions
+		}
+	}
+	contains := func(positions []int, pos int) bool {
+		i := sort.SearchInts(positions, pos)
+		return i < len(positions) && positions[i] == pos
+	}
+	for _, p := range index.postings[phrase[0]] {
+		for _, start := range p.positions {
+			match := true
+			for i := 1; i < len(phrase) && match; i++ {
+				match = contains(rest[i][p.card], start+i)
+			}
+			if match {
+				frequencies[p.card]++
+			}
+		}
+	}
+	return frequencies
+}
+
+const bm25K1 = 1.2
+const bm25B = 0.75
+
+// Cards containing any word or phrase of the query, best BM25 score first
+// (then by card number).
+func (index *TextIndex) Search(query string) []SearchResult {
+	scores := make(map[int]float64)
+	n := float64(len(index.lengths))
+	for _, clause := range parseTextQuery(query) {
+		frequencies := index.phraseFrequencies(clause)
+		df := float64(len(frequencies))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for card, tf := range frequencies {
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(index.lengths[card])/index.avgLength)
+			scores[card] += idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + norm)
+		}
+	}
+	results := make([]SearchResult, 0, len(scores))
+	for number, score := range scores {
+		results = append(results, SearchResult{Card: (*index.cards)[number], Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Card.Number < results[j].Card.Number
+	})
+	return results
+}
@@ -0,0 +1,90 @@
+package swcg
+
+import "testing"
+
+func TestStemWord(t *testing.T) {
+	// reference outputs of the Porter algorithm, from its original paper
+	tests := []struct {
+		word, stem string
+	}{
+		{"caresses", "caress"}, {"ponies", "poni"}, {"ties", "ti"}, {"caress", "caress"}, {"cats", "cat"},
+		{"feed", "feed"}, {"agreed", "agre"}, {"plastered", "plaster"}, {"bled", "bled"}, {"motoring", "motor"},
+		{"sing", "sing"}, {"conflated", "conflat"}, {"troubled", "troubl"}, {"sized", "size"}, {"hopping", "hop"},
+		{"tanned", "tan"}, {"falling", "fall"}, {"hissing", "hiss"}, {"fizzed", "fizz"}, {"failing", "fail"},
+		{"filing", "file"}, {"happy", "happi"}, {"sky", "sky"},
+		{"relational", "relat"}, {"conditional", "condit"}, {"rational", "ration"}, {"digitizer", "digit"},
+		{"vietnamization", "vietnam"}, {"predication", "predic"}, {"operator", "oper"}, {"feudalism", "feudal"},
+		{"decisiveness", "decis"}, {"hopefulness", "hope"}, {"callousness", "callous"}, {"sensitiviti", "sensit"},
+		{"triplicate", "triplic"}, {"formative", "form"}, {"formalize", "formal"}, {"electrical", "electr"},
+		{"goodness", "good"}, {"revival", "reviv"}, {"allowance", "allow"}, {"inference", "infer"},
+		{"airliner", "airlin"}, {"adjustable", "adjust"}, {"defensible", "defens"}, {"replacement", "replac"},
+		{"adoption", "adopt"}, {"communism", "commun"}, {"activate", "activ"}, {"homologous", "homolog"},
+		{"effective", "effect"}, {"probate", "probat"}, {"rate", "rate"}, {"cease", "ceas"},
+		{"controll", "control"}, {"roll", "roll"}, {"generalization", "gener"}, {"oscillators", "oscil"},
+		// "us" endings, kept as in Porter2
+		{"focus", "focus"}, {"focused", "focus"}, {"bonus", "bonus"},
+		// short words
+		{"as", "as"}, {"is", "is"},
+	}
+	for _, test := range tests {
+		if stem := StemWord(test.word); stem != test.stem {
+			t.Errorf("StemWord(%q) = %q, expected %q", test.word, stem, test.stem)
+		}
+	}
+}
+
+func testTextIndex() *TextIndex {
+	cards := CardMap{
+		1: {Name: "Shield Generator", Number: 1, Quote: "Deal 1 damage to the edge stack."},
+		2: {Name: "Edge Runner", Number: 2, Quote: "Runs along the stack."},
+		3: {Name: "Shielding Shields", Number: 3, Quote: "Shields shield shielded units."},
+		4: {Name: "Blockade", Number: 4, Quote: "Nothing to find here."},
+	}
+	return BuildTextIndex(&cards)
+}
+
+func searchNumbers(index *TextIndex, query string) []int {
+	numbers := make([]int, 0)
+	for _, r := range index.Search(query) {
+		numbers = append(numbers, r.Card.Number)
+	}
+	return numbers
+}
+
+func TestTextIndexSearch(t *testing.T) {
+	index := testTextIndex()
+	tests := []struct {
+		query    string
+		expected []int
+	}{
+		{"shields", []int{3, 1}},      // more occurrences rank first
+		{"edge", []int{2, 1}},         // same frequency, shorter card first
+		{`"edge stack"`, []int{1}},    // only consecutive terms
+		{`"stack edge"`, []int{}},
+		{`"generator deal"`, []int{}}, // phrases don't span fields
+		{`shield "edge stack"`, []int{1, 3}},
+		{"missing", []int{}},
+		{"", []int{}},
+	}
+	for _, test := range tests {
+		numbers := searchNumbers(index, test.query)
+		if len(numbers) != len(test.expected) {
+			t.Errorf("%q: got cards %v, expected %v", test.query, numbers, test.expected)
+			continue
+		}
+		for i := range numbers {
+			if numbers[i] != test.expected[i] {
+				t.Errorf("%q: got cards %v, expected %v", test.query, numbers, test.expected)
+				break
+			}
+		}
+	}
+}
+
+func TestTextIndexSearchTies(t *testing.T) {
+	cards := CardMap{7: {Name: "Twin", Number: 7}, 5: {Name: "Twin", Number: 5}}
+	results := BuildTextIndex(&cards).Search("twin")
+	if len(results) != 2 || results[0].Card.Number != 5 || results[0].Score != results[1].Score {
+		t.Errorf("equal scores should be ordered by card number, got %v", results)
+	}
+}
@@ -0,0 +1,236 @@
+package swcg
+
+import "fmt"
+import "math/rand/v2"
+import "runtime"
+import "strconv"
+import "sync"
+
+// Histograms -----------------------------------------------------------------
+
+// Histogram[v] is the number of samples of value v.
+type Histogram []int
+
+func (h *Histogram) Add(v int) {
+	if v < 0 {
+		v = 0
+	}
+	for len(*h) <= v {
+		*h = append(*h, 0)
+	}
+	(*h)[v]++
+}
+func (h *Histogram) Merge(other Histogram) {
+	for v, n := range other {
+		for len(*h) <= v {
+			*h = append(*h, 0)
+		}
+		(*h)[v] += n
+	}
+}
+func (h Histogram) Total() int {
+	total := 0
+	for _, n := range h {
+		total += n
+	}
+	return total
+}
+func (h Histogram) Mean() float64 {
+	total, sum := 0, 0
+	for v, n := range h {
+		total += n
+		sum += v*n
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(sum) / float64(total)
+}
+func (h Histogram) Probability(v int) float64 {
+	if v < 0 || v >= len(h) || h.Total() == 0 {
+		return 0
+	}
+	return float64(h[v]) / float64(h.Total())
+}
+// Smallest value v such that at least p of the samples are <= v.
+func (h Histogram) Percentile(p float64) int {
+	total := h.Total()
+	acc := 0
+	for v, n := range h {
+		acc += n
+		if float64(acc) >= p*float64(total) {
+			return v
+		}
+	}
+	return len(h)-1
+}
+
+// Simulation -----------------------------------------------------------------
+
+type SimulationConfig struct {
+	Games   int
+	Turns   int
+	Seed    uint64
+	Workers int // defaults to runtime.NumCPU()
+}
+
+type TurnStats struct {
+	Resources   Histogram // resources available during deployment
+	Spent       Histogram // cost of the cards played (castable cost)
+	ForceIcons  Histogram // Force icons left in hand, available for edge battles
+	UnusedCards Histogram // cards left in hand after deployment
+}
+
+type SimulationReport struct {
+	Games int
+	Turns []TurnStats
+}
+
+func (r *SimulationReport) merge(other *SimulationReport) {
+	r.Games += other.Games
+	for t := range r.Turns {
+		r.Turns[t].Resources.Merge(other.Turns[t].Resources)
+		r.Turns[t].Spent.Merge(other.Turns[t].Spent)
+		r.Turns[t].ForceIcons.Merge(other.Turns[t].ForceIcons)
+		r.Turns[t].UnusedCards.Merge(other.Turns[t].UnusedCards)
+	}
+}
+
+func (r *SimulationReport) DataCollection() *DataCollection {
+	formatStat := func(h Histogram) string {
+		return fmt.Sprintf("%.2f [%d-%d]", h.Mean(), h.Percentile(0.1), h.Percentile(0.9))
+	}
+	data := CreateDataCollection("Turn", "Resources", "Spent", "Force Icons", "Unused Cards")
+	for t, stats := range r.Turns {
+		data.AddRow(t+1, formatStat(stats.Resources), formatStat(stats.Spent), formatStat(stats.ForceIcons), formatStat(stats.UnusedCards))
+	}
+	return data
+}
+
+func (r *SimulationReport) Print() string {
+	return strconv.Itoa(r.Games)+" games, mean [10th-90th percentile]\n"+r.DataCollection().Print()
+}
+
+// Plays the deck solitaire for the configured number of games: 3 objectives
+// in play, an opening hand of HandSize cards, then every turn the hand is
+// refilled up to HandSize and the combination of cards using the most of the
+// available resources is played. Resources come from the affiliation, the
+// objectives in play and the cards already played. Fate cards are kept for
+// edge battles, the resource match rule and card effects are ignored.
+//
+// Each game gets its own random stream derived from the seed, so results
+// don't depend on the number of workers.
+func (d *Deck) SimulateResources(config SimulationConfig) *SimulationReport {
+	workers := config.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	commandDeck, objectiveDeck := d.CommandDeck(), d.ObjectiveDeck()
+	affiliationRessources := 0
+	if d.Affiliation != nil {
+		affiliationRessources = d.Affiliation.Ressources
+	}
+
+	reports := make([]*SimulationReport, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		reports[w] = &SimulationReport{Turns: make([]TurnStats, config.Turns)}
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			sim := &resourceSimulation{
+				commandDeck:   make([]*Card, len(commandDeck)),
+				objectiveDeck: make([]*Card, len(objectiveDeck)),
+				baseResources: affiliationRessources,
+				report:        reports[w],
+			}
+			for game := w; game < config.Games; game += workers {
+				copy(sim.commandDeck, commandDeck)
+				copy(sim.objectiveDeck, objectiveDeck)
+				sim.rng = rand.New(rand.NewPCG(config.Seed, uint64(game)))
+				sim.play(config.Turns)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	report := &SimulationReport{Turns: make([]TurnStats, config.Turns)}
+	for _, r := range reports {
+		report.merge(r)
+	}
+	return report
+}
+
+type resourceSimulation struct {
+	rng           *rand.Rand
+	commandDeck   []*Card
+	objectiveDeck []*Card
+	baseResources int
+	report        *SimulationReport
+}
+
+func (sim *resourceSimulation) play(turns int) {
+	sim.rng.Shuffle(len(sim.commandDeck), func(i, j int) { sim.commandDeck[i], sim.commandDeck[j] = sim.commandDeck[j], sim.commandDeck[i] })
+	sim.rng.Shuffle(len(sim.objectiveDeck), func(i, j int) { sim.objectiveDeck[i], sim.objectiveDeck[j] = sim.objectiveDeck[j], sim.objectiveDeck[i] })
+
+	resources := sim.baseResources
+	for i := 0; i < 3 && i < len(sim.objectiveDeck); i++ {
+		resources += sim.objectiveDeck[i].Ressources
+	}
+	deck := sim.commandDeck
+	hand := make([]*Card, 0, HandSize)
+
+	for t := 0; t < turns; t++ {
+		for len(hand) < HandSize && len(deck) > 0 {
+			hand = append(hand, deck[0])
+			deck = deck[1:]
+		}
+
+		available := resources
+		played, spent := bestDeployment(hand, available)
+		remaining := hand[:0]
+		forceIcons := 0
+		for i, c := range hand {
+			if played&(1<<uint(i)) != 0 {
+				if cardType := c.Type.GetType(); cardType == CardType_Unit || cardType == CardType_Enhancement {
+					resources += c.Ressources
+				}
+				continue
+			}
+			forceIcons += c.ForceIcons
+			remaining = append(remaining, c)
+		}
+		hand = remaining
+
+		stats := &sim.report.Turns[t]
+		stats.Resources.Add(available)
+		stats.Spent.Add(spent)
+		stats.ForceIcons.Add(forceIcons)
+		stats.UnusedCards.Add(len(hand))
+	}
+	sim.report.Games++
+}
+
+// Returns the bit set of the hand cards to play and their total cost,
+// spending as much of the resources as possible and preferring more cards.
+func bestDeployment(hand []*Card, resources int) (uint, int) {
+	bestSet, bestCost, bestCount := uint(0), 0, 0
+	for set := uint(1); set < 1<<uint(len(hand)); set++ {
+		cost, count := 0, 0
+		for i, c := range hand {
+			if set&(1<<uint(i)) == 0 {
+				continue
+			}
+			if c.Type.GetType() == CardType_Fate {
+				cost = resources+1
+				break
+			}
+			cost += c.Cost
+			count++
+		}
+		if cost <= resources && (cost > bestCost || cost == bestCost && count > bestCount) {
+			bestSet, bestCost, bestCount = set, cost, count
+		}
+	}
+	return bestSet, bestCost
+}
@@ -0,0 +1,22 @@
+package swcg
+
+import "reflect"
+import "testing"
+
+func TestSimulateResourcesDoesNotDependOnWorkers(t *testing.T) {
+	_, cache := AnalyzeDB(CreateDB())
+	d, err := CreateDeck(cache, Faction_Jedi, 1, 1, 2, 2, 3, 3, 4, 5, 6, 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	single := d.SimulateResources(SimulationConfig{Games: 200, Turns: 6, Seed: 3, Workers: 1})
+	if single.Games != 200 || len(single.Turns) != 6 || single.Turns[0].Resources.Total() != 200 {
+		t.Fatalf("expected 200 games of 6 turns, got %d games of %d turns", single.Games, len(single.Turns))
+	}
+	for _, workers := range []int{2, 3, 8} {
+		report := d.SimulateResources(SimulationConfig{Games: 200, Turns: 6, Seed: 3, Workers: workers})
+		if !reflect.DeepEqual(single, report) {
+			t.Errorf("%d workers gave\n%s\ninstead of\n%s", workers, report.Print(), single.Print())
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package swcg
+
+import "fmt"
+import "strings"
+
+// Synergy Expressions ---------------------------------------------------------
+//
+// Textual form of a SynergyInterface:
+//
+//   expr := term [ '-' | '+' ]
+//   term := 'type:' CardType | 'trait:' Trait | 'playarea'
+//         | 'not(' expr ')' | 'all(' [expr {',' expr}] ')' | 'any(' [expr {',' expr}] ')'
+//
+// all and any map to AccumulateSynergies and SynergyOptions, not to
+// InvertSynergy. Synergies are positive unless suffixed with '-' (a synergy
+// against the opponent's cards). A suffix on a composite applies to every
+// synergy inside it that doesn't carry its own suffix, and all the synergies
+// of a composite must agree (playarea having no polarity), e.g.
+//
+//   all(type:Unit, not(trait:Vehicule))
+//   any(trait:Character, trait:Creature)-
+//   any(playarea, trait:Character)-
+//
+// Type and trait names are matched case insensitively. A synergy list is a
+// comma separated list of expressions.
+
+type SynergyParseError struct {
+	Expr string
+	Pos  int
+	Msg  string
+}
+func (e *SynergyParseError) Error() string {
+	return fmt.Sprintf("synergy expression %q, position %d: %s", e.Expr, e.Pos, e.Msg)
+}
+
+func ParseSynergy(expr string) (SynergyInterface, error) {
+	p := &synergyParser{expr: expr}
+	syn, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpaces(); p.pos < len(p.expr) {
+		return nil, p.errorf("unexpected %q after synergy", p.expr[p.pos:])
+	}
+	return syn.build(), nil
+}
+
+func ParseSynergyList(expr string) (SynergyList, error) {
+	p := &synergyParser{expr: expr}
+	list := make(SynergyList, 0)
+	if p.skipSpaces(); p.pos == len(p.expr) {
+		return list, nil
+	}
+	for {
+		syn, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, syn.build())
+		if p.skipSpaces(); p.pos == len(p.expr) {
+			return list, nil
+		}
+		if err := p.expect(','); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func MustParseSynergy(expr string) SynergyInterface {
+	syn, err := ParseSynergy(expr)
+	if err != nil {
+		panic(err.Error())
+	}
+	return syn
+}
+
+// Printing
+
+// Polarity of the leaves of a synergy. The play area and empty composites
+// have none and take the polarity of the synergy containing them.
+type synergyPolarity int
+const (
+	polarityNone     synergyPolarity = iota
+	polarityPositive synergyPolarity = iota
+	polarityNegative synergyPolarity = iota
+	polarityMixed    synergyPolarity = iota
+)
+
+func leafPolarity(isPositive bool) synergyPolarity {
+	if isPositive {
+		return polarityPositive
+	}
+	return polarityNegative
+}
+
+func (p synergyPolarity) combine(other synergyPolarity) synergyPolarity {
+	switch {
+	case p == polarityNone:                 return other
+	case other == polarityNone, other == p: return p
+	}
+	return polarityMixed
+}
+
+// Expression of the synergy, parsed back by ParseSynergy. Fails on synergy
+// types the expressions can't represent.
+func FormatSynergy(s SynergyInterface) (string, error) {
+	text, polarity, err := formatSynergy(s)
+	if err != nil {
+		return "", err
+	}
+	if polarity == polarityNegative {
+		return text+"-", nil
+	}
+	return text, nil
+}
+
+func FormatSynergyList(list SynergyList) (string, error) {
+	parts := make([]string, len(list))
+	for i, s := range list {
+		var err error
+		if parts[i], err = FormatSynergy(s); err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// Returns the text without the trailing polarity of the synergy and the
+// polarity of its leaves. When they don't agree, the polarity is already
+// written on each of them (such composites don't parse back, as
+// IsPositiveEffect rejects them).
+func formatSynergy(s SynergyInterface) (string, synergyPolarity, error) {
+	switch syn := s.(type) {
+	case *CardTypeSynergy:
+		return "type:"+CardTypeNames[syn.Type], leafPolarity(syn.IsPositiveEff), nil
+	case *CardTraitSynergy:
+		return "trait:"+TraitNames[syn.Trait], leafPolarity(syn.IsPositiveEff), nil
+	case *PlayAreaSynergyType:
+		return "playarea", polarityNone, nil
+	case *InvertedSynergyType:
+		text, polarity, err := formatSynergy(syn.synergy)
+		if err != nil {
+			return "", polarityNone, err
+		}
+		return "not("+text+")", polarity, nil
+	case *OptionalSynergyType:
+		return formatComposite("any", syn.synergies)
+	case *AccumulationSynergyType:
+		return formatComposite("all", syn.synergies)
+	}
+	return "", polarityNone, fmt.Errorf("can't format unknown synergy %T", s)
+}
+
+func formatComposite(name string, list SynergyList) (string, synergyPolarity, error) {
+	texts := make([]string, len(list))
+	polarity := polarityNone
+	for i, s := range list {
+		var sPolarity synergyPolarity
+		var err error
+		if texts[i], sPolarity, err = formatSynergy(s); err != nil {
+			return "", polarityNone, err
+		}
+		polarity = polarity.combine(sPolarity)
+	}
+	if polarity == polarityMixed {
+		for i, s := range list {
+			texts[i], _ = FormatSynergy(s) // already formatted above
+		}
+	}
+	return name+"("+strings.Join(texts, ", ")+")", polarity, nil
+}
+
+// Parsing --------------------------------------------------------------------
+
+// Synergies are first parsed as a tree so that a polarity suffix on a
+// composite can be pushed down to its leaves before building them.
+type synergyNode struct {
+	kind        string // type, trait, playarea, not, all, any
+	value       int
+	children    []*synergyNode
+	hasPolarity bool
+	isPositive  bool
+	pos         int
+}
+
+func (n *synergyNode) setDefaultPolarity(isPositive bool) {
+	if n.hasPolarity {
+		return
+	}
+	n.isPositive = isPositive
+	for _, c := range n.children {
+		c.setDefaultPolarity(isPositive)
+	}
+}
+
+func (n *synergyNode) polarity() synergyPolarity {
+	switch n.kind {
+	case "type", "trait":
+		return leafPolarity(n.isPositive)
+	}
+	polarity := polarityNone
+	for _, c := range n.children {
+		polarity = polarity.combine(c.polarity())
+	}
+	return polarity
+}
+
+func (n *synergyNode) build() SynergyInterface {
+	switch n.kind {
+	case "type":
+		return TypeSynergy(CardType(n.value), n.isPositive)
+	case "trait":
+		return TraitSynergy(CardTraitType(n.value), n.isPositive)
+	case "playarea":
+		return PlayAreaSynergy()
+	case "not":
+		return InvertSynergy(n.children[0].build())
+	}
+	list := make(SynergyList, len(n.children))
+	for i, c := range n.children {
+		list[i] = c.build()
+	}
+	if n.kind == "any" {
+		return SynergyOptions(list)
+	}
+	return AccumulateSynergies(list)
+}
+
+type synergyParser struct {
+	expr string
+	pos  int
+}
+
+func (p *synergyParser) errorf(format string, args ...interface{}) error {
+	return &SynergyParseError{Expr: p.expr, Pos: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *synergyParser) skipSpaces() {
+	for p.pos < len(p.expr) && strings.ContainsRune(" \t\n\r", rune(p.expr[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *synergyParser) expect(c byte) error {
+	p.skipSpaces()
+	if p.pos >= len(p.expr) {
+		return p.errorf("expected %q but reached the end of the expression", c)
+	}
+	if p.expr[p.pos] != c {
+		return p.errorf("expected %q but found %q", c, p.expr[p.pos])
+	}
+	p.pos++
+	return nil
+}
+
+func (p *synergyParser) ident() string {
+	p.skipSpaces()
+	start := p.pos
+	for p.pos < len(p.expr) {
+		c := p.expr[p.pos]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			break
+		}
+		p.pos++
+	}
+	return p.expr[start:p.pos]
+}
+
+func (p *synergyParser) parseExpr() (*synergyNode, error) {
+	node, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+	if p.pos < len(p.expr) && (p.expr[p.pos] == '-' || p.expr[p.pos] == '+') {
+		if node.kind == "playarea" {
+			return nil, p.errorf("playarea synergies are always positive")
+		}
+		node.setDefaultPolarity(p.expr[p.pos] == '+')
+		node.hasPolarity = true
+		p.pos++
+	}
+	if node.polarity() == polarityMixed {
+		return nil, &SynergyParseError{Expr: p.expr, Pos: node.pos, Msg: "inconsistent positive and negative synergies in "+node.kind+"(...)"}
+	}
+	return node, nil
+}
+
+func (p *synergyParser) parseTerm() (*synergyNode, error) {
+	p.skipSpaces()
+	start := p.pos
+	name := strings.ToLower(p.ident())
+	node := &synergyNode{kind: name, isPositive: true, pos: start}
+	switch name {
+	case "":
+		if p.pos >= len(p.expr) {
+			return nil, p.errorf("expected a synergy but reached the end of the expression")
+		}
+		return nil, p.errorf("expected a synergy but found %q", p.expr[p.pos])
+	case "type", "trait":
+		if err := p.expect(':'); err != nil {
+			return nil, err
+		}
+		valueStart := p.pos
+		value := p.ident()
+		names := CardTypeNames[:]
+		if name == "trait" {
+			names = TraitNames[:]
+		}
+		index := lookupNameFold(names, value)
+		if index < 0 {
+			p.pos = valueStart
+			return nil, p.errorf("unknown %s %q (expected one of %s)", name, value, strings.Join(names, ", "))
+		}
+		node.value = index
+	case "playarea":
+	case "not", "all", "any":
+		if err := p.expect('('); err != nil {
+			return nil, err
+		}
+		if p.skipSpaces(); name != "not" && p.pos < len(p.expr) && p.expr[p.pos] == ')' {
+			p.pos++
+			break
+		}
+		for {
+			child, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			node.children = append(node.children, child)
+			if p.skipSpaces(); p.pos < len(p.expr) && p.expr[p.pos] == ',' && name != "not" {
+				p.pos++
+				continue
+			}
+			break
+		}
+		if err := p.expect(')'); err != nil {
+			return nil, err
+		}
+	default:
+		p.pos = start
+		return nil, p.errorf("unknown synergy %q (expected type:, trait:, playarea, not, all or any)", name)
+	}
+	return node, nil
+}
+
+func lookupNameFold(names []string, name string) int {
+	for i, n := range names {
+		if strings.EqualFold(n, name) {
+			return i
+		}
+	}
+	return -1
+}
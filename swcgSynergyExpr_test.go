@@ -0,0 +1,120 @@
+package swcg
+
+import "testing"
+
+func formatTestSynergy(t *testing.T, s SynergyInterface) string {
+	text, err := FormatSynergy(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return text
+}
+
+func TestFormatSynergyRoundTrip(t *testing.T) {
+	synergies := []SynergyInterface{
+		TypeSynergy(CardType_Unit, true),
+		TraitSynergy(Trait_Character, false),
+		PlayAreaSynergy(),
+		InvertSynergy(TraitSynergy(Trait_Vehicule, true)),
+		AccumulateSynergies(SynergyList{TypeSynergy(CardType_Unit, true), InvertSynergy(TraitSynergy(Trait_Vehicule, true))}),
+		SynergyOptions(SynergyList{TraitSynergy(Trait_Character, false), TraitSynergy(Trait_Creature, false)}),
+		SynergyOptions(SynergyList{PlayAreaSynergy(), TraitSynergy(Trait_Character, false)}),
+		SynergyOptions(SynergyList{PlayAreaSynergy()}),
+		AccumulateSynergies(SynergyList{}),
+		SynergyOptions(nil),
+		InvertSynergy(AccumulateSynergies(SynergyList{TypeSynergy(CardType_Event, false)})),
+	}
+	for _, c := range CreateDB() {
+		if enhancement, ok := c.Type.(*EnhancementCardType); ok {
+			synergies = append(synergies, enhancement.Synergies...)
+		}
+		for _, ability := range c.Abilities {
+			if a, ok := ability.(*CardAbility); ok {
+				synergies = append(synergies, a.Synergies...)
+				if a.TriggerFilter != nil {
+					synergies = append(synergies, a.TriggerFilter)
+				}
+			}
+		}
+	}
+
+	for _, s := range synergies {
+		text := formatTestSynergy(t, s)
+		parsed, err := ParseSynergy(text)
+		if err != nil {
+			t.Errorf("FormatSynergy gave %q which doesn't parse: %v", text, err)
+			continue
+		}
+		if again := formatTestSynergy(t, parsed); again != text {
+			t.Errorf("%q parsed and printed back as %q", text, again)
+		}
+		if parsed.IsPositiveEffect() != s.IsPositiveEffect() {
+			t.Errorf("%q: parsed positive effect %v, expected %v", text, parsed.IsPositiveEffect(), s.IsPositiveEffect())
+		}
+	}
+}
+
+func TestParseSynergy(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"type:unit", "type:Unit"},
+		{"trait:Character-", "trait:Character-"},
+		{" all( type:Unit , not(trait:Vehicule) ) ", "all(type:Unit, not(trait:Vehicule))"},
+		{"any(trait:Character, trait:Creature)-", "any(trait:Character, trait:Creature)-"},
+		{"any(trait:Character-, trait:Creature)-", "any(trait:Character, trait:Creature)-"},
+		{"any(playarea, trait:Character)-", "any(playarea, trait:Character)-"},
+		{"all()", "all()"},
+		{"any( )-", "any()"},
+	}
+	for _, test := range tests {
+		s, err := ParseSynergy(test.expr)
+		if err != nil {
+			t.Errorf("ParseSynergy(%q): %v", test.expr, err)
+			continue
+		}
+		if text := formatTestSynergy(t, s); text != test.expected {
+			t.Errorf("ParseSynergy(%q) printed as %q, expected %q", test.expr, text, test.expected)
+		}
+	}
+}
+
+func TestParseSynergyErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+	}{
+		{"", 0},
+		{"type:Starship", 5},
+		{"all(trait:Character, trait:Creature-)", 0},
+		{"not()", 4},
+		{"playarea-", 8},
+		{"any(type:Unit", 13},
+		{"type:Unit trait:Character", 10},
+	}
+	for _, test := range tests {
+		_, err := ParseSynergy(test.expr)
+		parseErr, ok := err.(*SynergyParseError)
+		if !ok {
+			t.Errorf("ParseSynergy(%q) returned %v, expected a SynergyParseError", test.expr, err)
+			continue
+		}
+		if parseErr.Pos != test.pos {
+			t.Errorf("ParseSynergy(%q) error at %d (%s), expected %d", test.expr, parseErr.Pos, parseErr.Msg, test.pos)
+		}
+	}
+}
+
+// A synergy type the expressions don't know.
+type unknownSynergy struct{ *CardTypeSynergy }
+
+func TestFormatUnknownSynergy(t *testing.T) {
+	unknown := unknownSynergy{TypeSynergy(CardType_Unit, true)}
+	if _, err := FormatSynergy(AccumulateSynergies(SynergyList{TypeSynergy(CardType_Unit, true), unknown})); err == nil {
+		t.Errorf("formatting an unknown synergy should fail")
+	}
+	if _, err := FormatSynergyList(SynergyList{InvertSynergy(unknown)}); err == nil {
+		t.Errorf("formatting a list with an unknown synergy should fail")
+	}
+}
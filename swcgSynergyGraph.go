@@ -0,0 +1,190 @@
+package swcg
+
+import "sort"
+
+// Synergy Graph --------------------------------------------------------------
+//
+// Directed graph of the card DB: every card has an edge to each card one of
+// its synergies (Card.GatherSynergies) is synergizing with. Negative edges
+// come from synergies against the opponent's cards. Nodes are card numbers.
+
+type SynergyEdge struct {
+	From     int
+	To       int
+	Positive bool
+}
+
+type SynergyGraph struct {
+	Nodes []int // card numbers, sorted
+	Out   map[int][]SynergyEdge
+	In    map[int][]SynergyEdge
+}
+
+func BuildSynergyGraph(cache *DataCache) *SynergyGraph {
+	graph := &SynergyGraph{Nodes: make([]int, 0, len(*cache.CardMap)),
+		Out: make(map[int][]SynergyEdge), In: make(map[int][]SynergyEdge)}
+	for number := range *cache.CardMap {
+		graph.Nodes = append(graph.Nodes, number)
+	}
+	sort.Ints(graph.Nodes)
+
+	for _, from := range graph.Nodes {
+		card := (*cache.CardMap)[from]
+		synergies := card.GatherSynergies()
+		for _, to := range graph.Nodes {
+			other := (*cache.CardMap)[to]
+			if to == from || other.Type == nil {
+				continue
+			}
+			positive, negative := false, false
+			for _, s := range synergies {
+				if s.IsSynergizingWith(other) {
+					if s.IsPositiveEffect() {
+						positive = true
+					} else {
+						negative = true
+					}
+				}
+			}
+			if positive {
+				graph.addEdge(SynergyEdge{From: from, To: to, Positive: true})
+			}
+			if negative {
+				graph.addEdge(SynergyEdge{From: from, To: to, Positive: false})
+			}
+		}
+	}
+	return graph
+}
+
+func (graph *SynergyGraph) addEdge(e SynergyEdge) {
+	graph.Out[e.From] = append(graph.Out[e.From], e)
+	graph.In[e.To] = append(graph.In[e.To], e)
+}
+
+// Every edge, ordered by source then target card number.
+func (graph *SynergyGraph) Edges() []SynergyEdge {
+	edges := make([]SynergyEdge, 0)
+	for _, n := range graph.Nodes {
+		edges = append(edges, graph.Out[n]...)
+	}
+	return edges
+}
+
+func (graph *SynergyGraph) OutDegree(number int) int { return len(graph.Out[number]) }
+func (graph *SynergyGraph) InDegree(number int) int  { return len(graph.In[number]) }
+
+// Strongly Connected Components ----------------------------------------------
+
+// Strongly connected components (Tarjan's algorithm), each one sorted by
+// card number. Components are ordered by decreasing size, then by their
+// first card. Cards without any cycle through them form their own
+// singleton component.
+func (graph *SynergyGraph) StronglyConnectedComponents() [][]int {
+	index := make(map[int]int)
+	lowLink := make(map[int]int)
+	onStack := make(map[int]bool)
+	stack := make([]int, 0)
+	components := make([][]int, 0)
+
+	var visit func(n int)
+	visit = func(n int) {
+		index[n] = len(index)
+		lowLink[n] = index[n]
+		stack = append(stack, n)
+		onStack[n] = true
+		for _, e := range graph.Out[n] {
+			if _, visited := index[e.To]; !visited {
+				visit(e.To)
+				lowLink[n] = min(lowLink[n], lowLink[e.To])
+			} else if onStack[e.To] {
+				lowLink[n] = min(lowLink[n], index[e.To])
+			}
+		}
+		if lowLink[n] == index[n] {
+			component := make([]int, 0)
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == n {
+					break
+				}
+			}
+			sort.Ints(component)
+			components = append(components, component)
+		}
+	}
+	for _, n := range graph.Nodes {
+		if _, visited := index[n]; !visited {
+			visit(n)
+		}
+	}
+	sort.SliceStable(components, func(i, j int) bool {
+		if len(components[i]) != len(components[j]) {
+			return len(components[i]) > len(components[j])
+		}
+		return components[i][0] < components[j][0]
+	})
+	return components
+}
+
+// Strongly connected components of more than one card: groups of cards
+// all enabling each other, directly or not.
+func (graph *SynergyGraph) Clusters() [][]int {
+	clusters := make([][]int, 0)
+	for _, c := range graph.StronglyConnectedComponents() {
+		if len(c) > 1 {
+			clusters = append(clusters, c)
+		}
+	}
+	return clusters
+}
+
+// Hubs -----------------------------------------------------------------------
+
+type SynergyDegree struct {
+	Card     int
+	Enables  int // positive out edges
+	Hinders  int // negative out edges
+	Enabled  int // positive in edges
+}
+
+func (graph *SynergyGraph) Degree(number int) SynergyDegree {
+	d := SynergyDegree{Card: number}
+	for _, e := range graph.Out[number] {
+		if e.Positive {
+			d.Enables++
+		} else {
+			d.Hinders++
+		}
+	}
+	for _, e := range graph.In[number] {
+		if e.Positive {
+			d.Enabled++
+		}
+	}
+	return d
+}
+
+// The n cards enabling the most other cards through positive synergies
+// (every card when n <= 0), ties broken by total out degree then by card
+// number.
+func (graph *SynergyGraph) MostEnabling(n int) []SynergyDegree {
+	degrees := make([]SynergyDegree, 0, len(graph.Nodes))
+	for _, number := range graph.Nodes {
+		degrees = append(degrees, graph.Degree(number))
+	}
+	sort.SliceStable(degrees, func(i, j int) bool {
+		a, b := degrees[i], degrees[j]
+		if a.Enables != b.Enables {
+			return a.Enables > b.Enables
+		}
+		return a.Enables+a.Hinders > b.Enables+b.Hinders
+	})
+	if n > 0 && n < len(degrees) {
+		degrees = degrees[:n]
+	}
+	return degrees
+}
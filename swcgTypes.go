@@ -131,11 +131,16 @@ func (syn *AccumulationSynergyType)IsSynergizingWithPlayArea() bool {
 	return false
 }
 
+// The play area synergies are always positive and only count when there is
+// nothing else.
 func (syn *AccumulationSynergyType) IsPositiveEffect() bool {
-	var isPositive bool
-	for i, s := range syn.synergies {
-		if i == 0 {
-			isPositive = s.IsPositiveEffect()
+	isPositive, first := len(syn.synergies) > 0, true
+	for _, s := range syn.synergies {
+		if s.IsSynergizingWithPlayArea() {
+			continue
+		}
+		if first {
+			isPositive, first = s.IsPositiveEffect(), false
 		} else if isPositive != s.IsPositiveEffect() {
 			panic("found inconsistent positive effect declaration in accumulation synergy...")
 		}
@@ -215,10 +220,15 @@ func Enhancement(Synergies SynergyList) *EnhancementCardType {
 type FateCardType struct {
 	SimpleCardType
 	EdgeBattlePriority int
+	CancelsEdgeBattle  bool // cancels the other fate cards and restarts the edge battle
 }
 func Fate(priority int) *FateCardType {
 	return &FateCardType{SimpleCardType: SimpleCardType{Type: CardType_Fate}, EdgeBattlePriority: priority}
 }
+// Same as a fate card with an Effect_CancelEdgeBattle action.
+func FateCancel(priority int) *FateCardType {
+	return &FateCardType{SimpleCardType: SimpleCardType{Type: CardType_Fate}, EdgeBattlePriority: priority, CancelsEdgeBattle: true}
+}
 
 type ObjectiveCardType struct {
 	SimpleCardType
@@ -257,6 +267,27 @@ var FactionNames [Faction_MAX]string = [Faction_MAX]string {
 	"DarkNeutral",
 }
 
+func (f CardFaction) Side() CardSide {
+	if f < Faction_Sith {
+		return Side_Light
+	}
+	return Side_Dark
+}
+func (f CardFaction) IsNeutral() bool {
+	return f == Faction_LightNeutral || f == Faction_DarkNeutral
+}
+
+type CardSide int
+const (
+	Side_Light CardSide = iota
+	Side_Dark  CardSide = iota
+	Side_MAX   CardSide = iota
+)
+var SideNames [Side_MAX]string = [Side_MAX]string {
+	"Light",
+	"Dark",
+}
+
 // Combat Icons  --------------------------------------------------------------
 
 type CombatIcon [2]int
@@ -300,8 +331,13 @@ func (a *BaseAbility) GetType() AbilityType {
 
 type CardAbility struct {
 	BaseAbility
-	Description string
-	Synergies   SynergyList
+	Description   string
+	Synergies     SynergyList
+	Trigger       EffectTrigger
+	TriggerFilter SynergyInterface
+	Costs         []CardEffect
+	Effects       []CardEffect
+	OncePerTurn   bool
 }
 func Ability(t AbilityType, desc string, syn SynergyList) *CardAbility {
 	if t == AbilityType_Keyword || t == AbilityType_Trait {
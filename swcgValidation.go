@@ -0,0 +1,96 @@
+package swcg
+
+import "strconv"
+import "strings"
+
+// Validation Rules -----------------------------------------------------------
+
+type ValidationRule int
+const (
+	Rule_DuplicateCardNumber   ValidationRule = iota
+	Rule_InvalidSetCardNumber  ValidationRule = iota
+	Rule_NonObjectiveFirstCard ValidationRule = iota
+	Rule_SetSlotAlreadyTaken   ValidationRule = iota
+	Rule_MissingCardType       ValidationRule = iota
+	Rule_DeckSetCount          ValidationRule = iota
+	Rule_DeckSetCopies         ValidationRule = iota
+	Rule_DeckIncompleteSet     ValidationRule = iota
+	Rule_DeckMixedSides        ValidationRule = iota
+	Rule_DeckNeutralFaction    ValidationRule = iota
+	Rule_DeckFactionRestricted ValidationRule = iota
+	Rule_DeckAffiliation       ValidationRule = iota
+	Rule_MAX                   ValidationRule = iota
+)
+var ValidationRuleNames [Rule_MAX]string = [Rule_MAX]string {
+	"DuplicateCardNumber",
+	"InvalidSetCardNumber",
+	"NonObjectiveFirstCard",
+	"SetSlotAlreadyTaken",
+	"MissingCardType",
+	"DeckSetCount",
+	"DeckSetCopies",
+	"DeckIncompleteSet",
+	"DeckMixedSides",
+	"DeckNeutralFaction",
+	"DeckFactionRestricted",
+	"DeckAffiliation",
+}
+
+// Validation Errors ----------------------------------------------------------
+
+// A single rule violation. CardNumber and SetId are 0 when the violation
+// isn't tied to a card or an objective set.
+type ValidationError struct {
+	CardNumber int
+	SetId      int
+	Rule       ValidationRule
+	Message    string
+}
+func (e ValidationError) Error() string {
+	out := ValidationRuleNames[e.Rule]
+	if e.CardNumber != 0 {
+		out += ": card #"+strconv.Itoa(e.CardNumber)
+	}
+	if e.SetId != 0 {
+		out += " (set #"+strconv.Itoa(e.SetId)+")"
+	}
+	return out+": "+e.Message
+}
+
+// Every violation found during a validation pass, in discovery order.
+type ValidationReport struct {
+	Errors []ValidationError
+}
+func (r *ValidationReport) Add(cardNumber int, setId int, rule ValidationRule, msg string) {
+	r.Errors = append(r.Errors, ValidationError{CardNumber: cardNumber, SetId: setId, Rule: rule, Message: msg})
+}
+func (r *ValidationReport) HasErrors() bool {
+	return len(r.Errors) > 0
+}
+func (r *ValidationReport) Error() string {
+	lines := make([]string, len(r.Errors))
+	for i, e := range r.Errors {
+		lines[i] = e.Error()
+	}
+	return strconv.Itoa(len(r.Errors))+" validation error(s):\n"+strings.Join(lines, "\n")
+}
+
+// Returns nil when the report is empty so callers can return it directly as
+// an error.
+func (r *ValidationReport) Err() error {
+	if !r.HasErrors() {
+		return nil
+	}
+	return r
+}
+
+// Returns the violations of the given rule.
+func (r *ValidationReport) ByRule(rule ValidationRule) []ValidationError {
+	filtered := make([]ValidationError, 0)
+	for _, e := range r.Errors {
+		if e.Rule == rule {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}